@@ -17,6 +17,7 @@ type Bot struct {
 	SyncCheckCallback   func(resp SyncCheckResponse) // 心跳回调
 	MessageHandler      MessageHandler               // 获取消息成功的handle
 	MessageErrorHandler func(err error) bool         // 获取消息发生错误的handle, 返回true则尝试继续监听
+	Events              *EventBus                    // 事件总线, 以上回调对应的事件都会在这里发布
 	isHot               bool                         // 是否为热登录模式
 	once                sync.Once
 	err                 error
@@ -116,6 +117,7 @@ func (b *Bot) LoginWithUUID(uuid string) error {
 	if b.UUIDCallback != nil {
 		b.UUIDCallback(uuid)
 	}
+	b.Events.Publish(&LoginEvent{State: LoginStateUUID, UUID: uuid})
 	for {
 		// 长轮询检查是否扫码登录
 		resp, err := b.Caller.CheckLogin(uuid)
@@ -128,12 +130,14 @@ func (b *Bot) LoginWithUUID(uuid string) error {
 			if b.LoginCallBack != nil {
 				b.LoginCallBack(resp.Raw)
 			}
+			b.Events.Publish(&LoginEvent{State: LoginStateConfirmed, UUID: uuid, Body: resp.Raw})
 			return b.HandleLogin(resp.Raw)
 		case StatusScanned:
 			// 执行扫码回调
 			if b.ScanCallBack != nil {
 				b.ScanCallBack(resp.Raw)
 			}
+			b.Events.Publish(&LoginEvent{State: LoginStateScanned, UUID: uuid, Body: resp.Raw})
 		case StatusTimeout:
 			return ErrLoginTimeout
 		case StatusWait:
@@ -196,23 +200,26 @@ func (b *Bot) WebInit() error {
 		return err
 	}
 	// 设置当前的用户
-	b.self = &Self{
+	self := &Self{
 		Bot:        b,
 		User:       &resp.User,
-		ContactMap: map[string]*User{},
+		contactMap: map[string]*User{},
+		details:    newDetailCache(defaultDetailCacheTTL, defaultDetailCacheSize),
 	}
-	b.self.formatEmoji()
+	self.formatEmoji()
 	b.Storage.Response = resp
 	for _, user := range resp.ContactList {
 		u := user
 		u.formatEmoji()
-		b.self.ContactMap[u.UserName] = &u
+		self.contactMap[u.UserName] = &u
 	}
+	b.self = self
 
 	// 通知手机客户端已经登录
 	if err = b.Caller.WebWxStatusNotify(req, resp, info); err != nil {
 		return err
 	}
+	b.Events.Publish(&LoginEvent{State: LoginStateOnline, UUID: b.uuid})
 	// 开启协程，轮询获取是否有新的消息返回
 
 	// FIX: 当bot在线的情况下执行热登录,会开启多次事件监听
@@ -226,7 +233,9 @@ func (b *Bot) WebInit() error {
 				continue
 			}
 			// 判断是否继续, 如果不继续则退出
-			if goon := b.MessageErrorHandler(err); !goon {
+			goon := b.MessageErrorHandler(err)
+			b.Events.Publish(&ErrorEvent{Err: err, Retry: goon})
+			if !goon {
 				break
 			}
 		}
@@ -251,6 +260,7 @@ func (b *Bot) syncCheck() error {
 		if b.SyncCheckCallback != nil {
 			b.SyncCheckCallback(*resp)
 		}
+		b.Events.Publish(&SyncCheckEvent{Response: *resp})
 		// 如果不是正常的状态码返回，发生了错误，直接退出
 		if !resp.Success() {
 			return resp
@@ -261,11 +271,12 @@ func (b *Bot) syncCheck() error {
 			if err != nil {
 				return err
 			}
-			if b.MessageHandler == nil {
-				continue
-			}
 			for _, message := range messages {
 				message.init(b)
+				b.publishMessage(message)
+				if b.MessageHandler == nil {
+					continue
+				}
 				// 默认同步调用
 				// 如果异步调用则需自行处理
 				// 如配合 openwechat.MessageMatchDispatcher 使用
@@ -276,6 +287,18 @@ func (b *Bot) syncCheck() error {
 	return err
 }
 
+// 发布新消息事件, 撤回和好友请求会额外发布对应的事件
+func (b *Bot) publishMessage(message *Message) {
+	b.Events.Publish(&MessageEvent{Message: message})
+	if message.IsRecalled() {
+		revoke, _ := message.RevokeMsg()
+		b.Events.Publish(&RecallEvent{Message: message, Revoke: revoke})
+	}
+	if message.IsFriendAdd() {
+		b.Events.Publish(&FriendRequestEvent{Message: message})
+	}
+}
+
 // 当获取消息发生错误时, 默认的错误处理行为
 func (b *Bot) stopSyncCheck(err error) bool {
 	if IsNetworkError(err) {
@@ -300,12 +323,15 @@ func (b *Bot) syncMessage() ([]*Message, error) {
 	delContactList := resp.DelContactList
 
 	for _, modContact := range modContactList {
-		c := *modContact
-		b.self.ContactMap[modContact.UserName] = &c
+		modContact.formatEmoji()
 	}
+	b.self.applyContacts(modContactList, delContactList)
 
-	for _, delContact := range delContactList {
-		delete(b.self.ContactMap, delContact.UserName)
+	if len(modContactList) > 0 {
+		b.Events.Publish(&ContactModifiedEvent{Users: modContactList})
+	}
+	if len(delContactList) > 0 {
+		b.Events.Publish(&ContactDeletedEvent{Users: delContactList})
 	}
 	return resp.AddMsgList, nil
 }
@@ -328,6 +354,7 @@ func (b *Bot) Exit() {
 	if b.LogoutCallBack != nil {
 		b.LogoutCallBack(b)
 	}
+	b.Events.Publish(&LoginEvent{State: LoginStateOffline, UUID: b.uuid, Err: b.err})
 	b.self = nil
 	b.cancel()
 }
@@ -369,7 +396,7 @@ func NewBot(mode Mode) *Bot {
 	caller := DefaultCaller()
 	caller.Client.SetMode(mode)
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Bot{Caller: caller, Storage: &Storage{}, context: ctx, cancel: cancel}
+	return &Bot{Caller: caller, Storage: &Storage{}, Events: NewEventBus(), context: ctx, cancel: cancel}
 }
 
 // GetQrcodeUrl 通过uuid获取登录二维码的url
@@ -1,3 +1,7 @@
+//go:build integration
+
+// The tests log in by scanning the QR code, run them with -tags integration.
+
 package client
 
 import (
@@ -7,7 +11,7 @@ import (
 )
 
 func TestLogin(t *testing.T) {
-	bot := DefaultBot(Desktop)
+	bot := NewBot(Desktop)
 	bot.LoginCallBack = func(body []byte) {
 		t.Log("login success")
 	}
@@ -17,7 +21,7 @@ func TestLogin(t *testing.T) {
 }
 
 func TestLogout(t *testing.T) {
-	bot := DefaultBot(Desktop)
+	bot := NewBot(Desktop)
 	bot.LoginCallBack = func(body []byte) {
 		t.Log("login success")
 	}
@@ -37,7 +41,7 @@ func TestLogout(t *testing.T) {
 }
 
 func TestMessageHandle(t *testing.T) {
-	bot := DefaultBot(Desktop)
+	bot := NewBot(Desktop)
 	bot.MessageHandler = func(msg *Message) {
 		if msg.IsText() && msg.Content == "ping" {
 			msg.ReplyText("pong")
@@ -51,7 +55,7 @@ func TestMessageHandle(t *testing.T) {
 }
 
 func TestFriends(t *testing.T) {
-	bot := DefaultBot(Desktop)
+	bot := NewBot(Desktop)
 	if err := bot.Login(); err != nil {
 		t.Error(err)
 		return
@@ -70,7 +74,7 @@ func TestFriends(t *testing.T) {
 }
 
 func TestGroups(t *testing.T) {
-	bot := DefaultBot(Desktop)
+	bot := NewBot(Desktop)
 	if err := bot.Login(); err != nil {
 		t.Error(err)
 		return
@@ -89,7 +93,7 @@ func TestGroups(t *testing.T) {
 }
 
 func TestPinUser(t *testing.T) {
-	bot := DefaultBot(Desktop)
+	bot := NewBot(Desktop)
 	if err := bot.Login(); err != nil {
 		t.Error(err)
 		return
@@ -106,16 +110,16 @@ func TestPinUser(t *testing.T) {
 	}
 	if friends.Count() > 0 {
 		f := friends.First()
-		f.Pin()
+		user.PinUser(f.User)
 		time.Sleep(time.Second * 5)
-		f.UnPin()
+		user.UnPinUser(f.User)
 	}
 }
 
 func TestSender(t *testing.T) {
-	bot := DefaultBot(Desktop)
+	bot := NewBot(Desktop)
 	bot.MessageHandler = func(msg *Message) {
-		if msg.IsSendByGroup() {
+		if msg.Category == CategoryGroup {
 			fmt.Println(msg.SenderInGroup())
 		} else {
 			fmt.Println(msg.Sender())
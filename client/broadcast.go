@@ -0,0 +1,155 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"text/template"
+	"time"
+)
+
+// BroadcastPayload 群发的消息内容
+// Text 为 text/template 模板, 可使用 BroadcastTemplateData 中的变量
+//      payload := &BroadcastPayload{Text: "{{.Name}}, 明天上午9点开会"}
+type BroadcastPayload struct {
+	Text     string
+	FilePath string // 图片、视频或文件的路径, 为空则只发送文本
+}
+
+// BroadcastTemplateData 群发模板中每个联系人的变量
+type BroadcastTemplateData struct {
+	Name        string // 备注名, 没有备注时为昵称
+	NickName    string
+	RemarkName  string
+	DisplayName string
+	Alias       string
+}
+
+// BroadcastOption 群发的发送节奏, 避免发送过快被限制
+type BroadcastOption struct {
+	Interval time.Duration // 每个联系人之间的发送间隔
+	Jitter   time.Duration // 在间隔的基础上随机增加的时长
+	// Sent 返回联系人已经发送过的部分, 中断后继续时跳过这些部分, 为nil则全部发送
+	Sent func(user *User) BroadcastParts
+}
+
+// BroadcastParts 群发内容中已发送的部分
+type BroadcastParts struct {
+	Text bool
+	File bool
+}
+
+// BroadcastResult 群发给单个联系人的结果, Sent 包括之前已经发送的部分
+type BroadcastResult struct {
+	User         *User
+	SentMessages []*SentMessage
+	Sent         BroadcastParts
+	Err          error
+}
+
+func newBroadcastTemplateData(user *User) BroadcastTemplateData {
+	name := user.RemarkName
+	if name == "" {
+		name = user.NickName
+	}
+	return BroadcastTemplateData{
+		Name:        name,
+		NickName:    user.NickName,
+		RemarkName:  user.RemarkName,
+		DisplayName: user.DisplayName,
+		Alias:       user.Alias,
+	}
+}
+
+func (s *Self) sendFileByTypeToUser(user *User, filePath string) (*SentMessage, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	switch getMessageType(file.Name()) {
+	case pic:
+		return s.sendImageToUser(user, file)
+	case video:
+		return s.sendVideoToUser(user, file)
+	}
+	return s.sendFileToUser(user, file)
+}
+
+// SendFileByTypeToUser 根据文件扩展名发送图片、视频或文件给联系人(好友、群组)
+//      self.SendFileByTypeToUser(friend.User, "report.pdf")
+func (s *Self) SendFileByTypeToUser(user *User, filePath string) (*SentMessage, error) {
+	return s.sendFileByTypeToUser(user, filePath)
+}
+
+// 群发给单个联系人, 先发送文本再发送文件, 跳过sent中已发送的部分
+func (s *Self) broadcastToUser(user *User, tpl *template.Template, payload *BroadcastPayload, sent BroadcastParts) *BroadcastResult {
+	result := &BroadcastResult{User: user, Sent: sent}
+	if tpl != nil && !sent.Text {
+		var buffer bytes.Buffer
+		if result.Err = tpl.Execute(&buffer, newBroadcastTemplateData(user)); result.Err != nil {
+			return result
+		}
+		sentMessage, err := s.sendTextToUser(user, buffer.String())
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.SentMessages = append(result.SentMessages, sentMessage)
+		result.Sent.Text = true
+	}
+	if payload.FilePath != "" && !sent.File {
+		sentMessage, err := s.sendFileByTypeToUser(user, payload.FilePath)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.SentMessages = append(result.SentMessages, sentMessage)
+		result.Sent.File = true
+	}
+	return result
+}
+
+// Broadcast 群发消息给多个联系人(好友、群组)
+// 每个联系人发送完成后都会执行onResult回调, 可用于记录进度以便中断后继续
+// ctx 取消时停止发送并返回 ctx.Err()
+func (s *Self) Broadcast(ctx context.Context, users Members, payload *BroadcastPayload, option BroadcastOption, onResult func(result *BroadcastResult)) error {
+	if payload.Text == "" && payload.FilePath == "" {
+		return errors.New("empty broadcast payload")
+	}
+	var tpl *template.Template
+	if payload.Text != "" {
+		var err error
+		if tpl, err = template.New("broadcast").Parse(payload.Text); err != nil {
+			return err
+		}
+	}
+	for index, user := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var sent BroadcastParts
+		if option.Sent != nil {
+			sent = option.Sent(user)
+		}
+		result := s.broadcastToUser(user, tpl, payload, sent)
+		if onResult != nil {
+			onResult(result)
+		}
+		if index == len(users)-1 {
+			break
+		}
+		delay := option.Interval
+		if option.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(option.Jitter)))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return nil
+}
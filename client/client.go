@@ -44,6 +44,9 @@ type Client struct {
 	mode    Mode
 	mu      sync.Mutex
 	cookies map[string][]*http.Cookie
+	// RequestObserver 每个请求结束后调用, 用于统计耗时和错误
+	// endpoint 为 Endpoint 返回的接口名
+	RequestObserver func(endpoint string, elapsed time.Duration, resp *http.Response, err error)
 }
 
 func NewClient() *Client {
@@ -75,10 +78,14 @@ func (c *Client) do(req *http.Request) (*http.Response, error) {
 	for _, hook := range c.HttpHooks {
 		hook.BeforeRequest(req)
 	}
+	start := time.Now()
 	resp, err := c.Client.Do(req)
 	if err != nil {
 		err = NetworkErr{error: err}
 	}
+	if c.RequestObserver != nil {
+		c.RequestObserver(Endpoint(req.URL), time.Since(start), resp, err)
+	}
 	for _, hook := range c.HttpHooks {
 		hook.AfterRequest(resp, err)
 	}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDetailBatchSize   = 50 // webwxbatchgetcontact 一次最多获取50个
+	defaultDetailConcurrency = 4
+	defaultDetailInterval    = 200 * time.Millisecond
+)
+
+// DetailOption 批量获取联系人详情的选项
+type DetailOption struct {
+	BatchSize   int                  // 每次请求的联系人数, 默认50
+	Concurrency int                  // 同时进行的请求数, 默认4
+	Interval    time.Duration        // 相邻两次请求的最小间隔, 默认200ms, 负数则不限制
+	Progress    func(DetailProgress) // 每完成一批回调一次, 不会并发调用
+}
+
+func (o DetailOption) withDefaults() DetailOption {
+	if o.BatchSize <= 0 || o.BatchSize > defaultDetailBatchSize {
+		o.BatchSize = defaultDetailBatchSize
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultDetailConcurrency
+	}
+	if o.Interval == 0 {
+		o.Interval = defaultDetailInterval
+	}
+	return o
+}
+
+// DetailProgress 批量获取联系人详情的进度, 以联系人数计
+type DetailProgress struct {
+	Done   int
+	Failed int
+	Total  int
+}
+
+// BatchDetailError 部分批次获取失败, 失败的联系人保留原来的信息
+type BatchDetailError struct {
+	Failed int
+	Total  int
+	Errs   []error
+}
+
+func (e *BatchDetailError) Error() string {
+	return fmt.Sprintf("failed to fetch %d of %d contacts: %v", e.Failed, e.Total, e.Errs[0])
+}
+
+func (e *BatchDetailError) Unwrap() error {
+	return e.Errs[0]
+}
+
+type detailBatch struct {
+	index   int
+	members Members
+}
+
+// FetchDetails 分批并发获取联系人的详情, 群组的详情中带有群成员列表
+// 返回的联系人按members的顺序排列, 获取失败的批次保留members中原来的联系人
+//		details, err := self.FetchDetails(members, DetailOption{Concurrency: 8, Progress: func(p DetailProgress) {
+//			fmt.Printf("%d/%d\n", p.Done, p.Total)
+//		}})
+func (s *Self) FetchDetails(members Members, option DetailOption) (Members, error) {
+	option = option.withDefaults()
+	var batches []detailBatch
+	for start := 0; start < len(members); start += option.BatchSize {
+		end := start + option.BatchSize
+		if end > len(members) {
+			end = len(members)
+		}
+		batches = append(batches, detailBatch{index: len(batches), members: members[start:end]})
+	}
+
+	var limiter <-chan time.Time
+	if option.Interval > 0 {
+		ticker := time.NewTicker(option.Interval)
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		progress = DetailProgress{Total: len(members)}
+		results  = make([]Members, len(batches))
+		errs     []error
+	)
+	queue := make(chan detailBatch)
+	request := s.Bot.Storage.Request
+	for i := 0; i < option.Concurrency && i < len(batches); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range queue {
+				if limiter != nil {
+					<-limiter
+				}
+				details, err := s.Bot.Caller.WebWxBatchGetContact(batch.members, request)
+				mu.Lock()
+				if err != nil {
+					results[batch.index] = batch.members
+					progress.Failed += len(batch.members)
+					errs = append(errs, err)
+				} else {
+					details.init()
+					for _, detail := range details {
+						detail.MemberList.init()
+						if s.details != nil {
+							s.details.put(detail)
+						}
+					}
+					results[batch.index] = details
+					progress.Done += len(batch.members)
+				}
+				if option.Progress != nil {
+					option.Progress(progress)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, batch := range batches {
+		queue <- batch
+	}
+	close(queue)
+	wg.Wait()
+
+	merged := make(Members, 0, len(members))
+	for _, result := range results {
+		merged = append(merged, result...)
+	}
+	if len(errs) > 0 {
+		return merged, &BatchDetailError{Failed: progress.Failed, Total: progress.Total, Errs: errs}
+	}
+	return merged, nil
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDetailCacheTTL  = 10 * time.Minute
+	defaultDetailCacheSize = 2000
+)
+
+// DetailCacheStats 联系人详情缓存的统计
+type DetailCacheStats struct {
+	Size      int    // 当前缓存的联系人数
+	Hits      uint64 // 命中缓存的次数
+	Misses    uint64 // 发起请求的次数
+	Coalesced uint64 // 等待同一个UserName正在进行的请求的次数
+	Evicted   uint64 // 超出容量被淘汰的次数
+}
+
+type detailEntry struct {
+	user    User
+	expires time.Time
+}
+
+var errDetailFetchAborted = errors.New("contact detail request aborted")
+
+// 正在进行的请求, 同一个UserName的并发查询共用一个请求
+type detailCall struct {
+	done        chan struct{}
+	user        *User
+	err         error
+	invalidated bool
+}
+
+// 联系人详情缓存, 按最近使用淘汰, 返回的都是副本
+type detailCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	lru     *list.List // 最近使用的在前面, 元素的值为UserName
+	entries map[string]*list.Element
+	values  map[string]*detailEntry
+	calls   map[string]*detailCall
+	stats   DetailCacheStats
+}
+
+func newDetailCache(ttl time.Duration, size int) *detailCache {
+	return &detailCache{
+		ttl:     ttl,
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		values:  make(map[string]*detailEntry),
+		calls:   make(map[string]*detailCall),
+	}
+}
+
+// 获取联系人详情, 缓存中没有或者已经过期则调用fetch
+func (c *detailCache) get(username string, fetch func(username string) (*User, error)) (*User, error) {
+	c.mu.Lock()
+	if entry, ok := c.values[username]; ok {
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(c.entries[username])
+			c.stats.Hits++
+			user := entry.user
+			c.mu.Unlock()
+			return &user, nil
+		}
+		c.remove(username)
+	}
+	if call, ok := c.calls[username]; ok {
+		c.stats.Coalesced++
+		c.mu.Unlock()
+		<-call.done
+		if call.err != nil {
+			return nil, call.err
+		}
+		user := *call.user
+		return &user, nil
+	}
+	call := &detailCall{done: make(chan struct{})}
+	c.calls[username] = call
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	func() {
+		// fetch panic时也要唤醒等待的查询, 否则之后对该UserName的查询会一直阻塞
+		defer c.finish(username, call)
+		call.err = errDetailFetchAborted
+		call.user, call.err = fetch(username)
+	}()
+	if call.err != nil {
+		return nil, call.err
+	}
+	user := *call.user
+	return &user, nil
+}
+
+// 结束正在进行的请求并唤醒等待的查询
+func (c *detailCache) finish(username string, call *detailCall) {
+	c.mu.Lock()
+	delete(c.calls, username)
+	// 请求期间收到了变更, 结果可能已经过时, 不缓存
+	if call.err == nil && !call.invalidated {
+		c.set(call.user)
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// 只从缓存中获取联系人详情, 不发起请求, 也不计入统计
+func (c *detailCache) peek(username string) (*User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.values[username]
+	if !ok || !time.Now().Before(entry.expires) {
+		return nil, false
+	}
+	user := entry.user
+	return &user, true
+}
+
+// 放入最新的联系人详情
+func (c *detailCache) put(user *User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(user)
+}
+
+func (c *detailCache) set(user *User) {
+	if el, ok := c.entries[user.UserName]; ok {
+		c.lru.MoveToFront(el)
+	} else {
+		c.entries[user.UserName] = c.lru.PushFront(user.UserName)
+	}
+	c.values[user.UserName] = &detailEntry{user: *user, expires: time.Now().Add(c.ttl)}
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back().Value.(string))
+		c.stats.Evicted++
+	}
+}
+
+func (c *detailCache) remove(username string) {
+	if el, ok := c.entries[username]; ok {
+		c.lru.Remove(el)
+		delete(c.entries, username)
+		delete(c.values, username)
+	}
+}
+
+// 联系人发生变更后使缓存失效
+func (c *detailCache) invalidate(usernames ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, username := range usernames {
+		c.remove(username)
+		if call, ok := c.calls[username]; ok {
+			call.invalidated = true
+		}
+	}
+}
+
+func (c *detailCache) statistics() DetailCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.lru.Len()
+	return stats
+}
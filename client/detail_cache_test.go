@@ -0,0 +1,132 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDetailCacheCoalesce(t *testing.T) {
+	c := newDetailCache(time.Minute, 10)
+	var fetches int32
+	release := make(chan struct{})
+	fetch := func(username string) (*User, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return &User{UserName: username, NickName: "nick"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := c.get("@a", fetch)
+			if err != nil || user.NickName != "nick" {
+				t.Errorf("get = %v, %v", user, err)
+			}
+			// every caller gets its own copy
+			user.NickName = "changed"
+		}()
+	}
+	for c.statistics().Coalesced < 7 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("fetched %d times, want 1", n)
+	}
+	if user, ok := c.peek("@a"); !ok || user.NickName != "nick" {
+		t.Errorf("peek = %v, %v, want the cached detail", user, ok)
+	}
+	c.get("@a", fetch)
+	if stats := c.statistics(); stats.Hits != 1 || stats.Misses != 1 || stats.Size != 1 {
+		t.Errorf("stats = %+v, want 1 hit, 1 miss, size 1", stats)
+	}
+}
+
+func TestDetailCacheInvalidateDuringFetch(t *testing.T) {
+	c := newDetailCache(time.Minute, 10)
+	user, err := c.get("@a", func(username string) (*User, error) {
+		// the contact changed while its detail was requested
+		c.invalidate(username)
+		return &User{UserName: username}, nil
+	})
+	if err != nil || user.UserName != "@a" {
+		t.Fatalf("get = %v, %v", user, err)
+	}
+	if _, ok := c.peek("@a"); ok {
+		t.Error("stale detail cached after invalidate")
+	}
+
+	c.put(&User{UserName: "@b"})
+	c.invalidate("@b")
+	if _, ok := c.peek("@b"); ok {
+		t.Error("detail still cached after invalidate")
+	}
+}
+
+func TestDetailCachePanicWakesWaiters(t *testing.T) {
+	c := newDetailCache(time.Minute, 10)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	waited := make(chan error)
+	go func() {
+		defer func() { recover() }()
+		c.get("@a", func(username string) (*User, error) {
+			close(started)
+			<-release
+			panic("broken fetch")
+		})
+	}()
+	<-started
+	go func() {
+		_, err := c.get("@a", func(username string) (*User, error) {
+			return nil, errors.New("unexpected fetch")
+		})
+		waited <- err
+	}()
+	for c.statistics().Coalesced < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	select {
+	case err := <-waited:
+		if !errors.Is(err, errDetailFetchAborted) {
+			t.Errorf("waiter err = %v, want %v", err, errDetailFetchAborted)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter not woken after fetch panic")
+	}
+
+	// the next query fetches again instead of blocking
+	user, err := c.get("@a", func(username string) (*User, error) {
+		return &User{UserName: username}, nil
+	})
+	if err != nil || user.UserName != "@a" {
+		t.Errorf("get after panic = %v, %v", user, err)
+	}
+}
+
+func TestDetailCacheEvict(t *testing.T) {
+	c := newDetailCache(time.Minute, 2)
+	for _, username := range []string{"@a", "@b", "@c"} {
+		c.put(&User{UserName: username})
+	}
+	if _, ok := c.peek("@a"); ok {
+		t.Error("least recently used detail not evicted")
+	}
+	if stats := c.statistics(); stats.Size != 2 || stats.Evicted != 1 {
+		t.Errorf("stats = %+v, want size 2 and 1 evicted", stats)
+	}
+
+	expired := newDetailCache(-time.Second, 2)
+	expired.put(&User{UserName: "@a"})
+	if _, ok := expired.peek("@a"); ok {
+		t.Error("expired detail returned")
+	}
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newDetailServer answers webwxbatchgetcontact with a signature for every contact,
+// except batches containing failUserName which get an error response.
+func newDetailServer(t *testing.T, failUserName string) (*Self, func()) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ List UserDetailItemList }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		var resp WebWxBatchContactResponse
+		for _, item := range body.List {
+			if item.UserName == failUserName {
+				resp = WebWxBatchContactResponse{BaseResponse: BaseResponse{Ret: 1101}}
+				break
+			}
+			resp.ContactList = append(resp.ContactList, &User{UserName: item.UserName, Signature: "detail"})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	client := DefaultClient()
+	client.Client = server.Client()
+	client.Domain = WechatDomain(strings.TrimPrefix(server.URL, "https://"))
+	bot := &Bot{Caller: NewCaller(client), Events: NewEventBus(), Storage: &Storage{Request: &BaseRequest{}}}
+	s := newTestSelf(7)
+	s.Bot = bot
+	return s, server.Close
+}
+
+func TestFetchDetailsPartialBatch(t *testing.T) {
+	s, stop := newDetailServer(t, "@f3")
+	defer stop()
+	members, _ := s.Members(false)
+
+	var last DetailProgress
+	details, err := s.FetchDetails(members, DetailOption{BatchSize: 2, Concurrency: 3, Interval: -1, Progress: func(p DetailProgress) {
+		last = p
+	}})
+	var batchErr *BatchDetailError
+	if !errors.As(err, &batchErr) || batchErr.Failed != 2 || batchErr.Total != 7 {
+		t.Fatalf("err = %v, want 2 of 7 contacts failed", err)
+	}
+	if last != (DetailProgress{Done: 5, Failed: 2, Total: 7}) {
+		t.Errorf("last progress = %+v", last)
+	}
+	if len(details) != len(members) {
+		t.Fatalf("got %d details, want %d", len(details), len(members))
+	}
+	for i, detail := range details {
+		// the failed batch keeps the original contacts, in place
+		want := "detail"
+		if i == 2 || i == 3 {
+			want = ""
+		}
+		if detail.UserName != members[i].UserName || detail.Signature != want {
+			t.Errorf("details[%d] = %s %q, want %s %q", i, detail.UserName, detail.Signature, members[i].UserName, want)
+		}
+	}
+	if _, ok := s.CachedUserDetail("@f0"); !ok {
+		t.Error("fetched detail not cached")
+	}
+	if _, ok := s.CachedUserDetail("@f3"); ok {
+		t.Error("failed detail cached")
+	}
+
+	s.mergeDetails(details)
+	if user, _ := s.FindContactByUserName("@f4"); user.Signature != "detail" {
+		t.Error("fetched detail not merged")
+	}
+	if user, _ := s.FindContactByUserName("@f2"); user.NickName != "friend 2" {
+		t.Error("contact of the failed batch lost")
+	}
+}
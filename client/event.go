@@ -0,0 +1,314 @@
+package client
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// EventType 事件类型
+type EventType int
+
+const (
+	EventLogin           EventType = iota + 1 // 登录状态变化
+	EventSyncCheck                            // 心跳
+	EventMessage                              // 新消息
+	EventContactModified                      // 联系人变更
+	EventContactDeleted                       // 联系人删除
+	EventMessageRecalled                      // 消息撤回
+	EventFriendRequest                        // 好友请求
+	EventError                                // 消息同步发生错误
+	EventMessageSent                          // 消息发送成功
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventLogin:
+		return "Login"
+	case EventSyncCheck:
+		return "SyncCheck"
+	case EventMessage:
+		return "Message"
+	case EventContactModified:
+		return "ContactModified"
+	case EventContactDeleted:
+		return "ContactDeleted"
+	case EventMessageRecalled:
+		return "MessageRecalled"
+	case EventFriendRequest:
+		return "FriendRequest"
+	case EventError:
+		return "Error"
+	case EventMessageSent:
+		return "MessageSent"
+	}
+	return "Unknown"
+}
+
+// Event 事件, 根据 Type 断言成具体的事件类型
+//		bus.Subscribe(func(e Event) {
+//			if e, ok := e.(*MessageEvent); ok {
+//				fmt.Println(e.Message)
+//			}
+//		}, SubscribeOption{Types: []EventType{EventMessage}})
+type Event interface {
+	Type() EventType
+}
+
+// LoginState 登录状态
+type LoginState int
+
+const (
+	LoginStateUUID      LoginState = iota + 1 // 获取到二维码
+	LoginStateScanned                         // 已扫码
+	LoginStateConfirmed                       // 已在手机上确认登录
+	LoginStateOnline                          // 初始化完成, 开始同步消息
+	LoginStateOffline                         // 已退出
+)
+
+func (s LoginState) String() string {
+	switch s {
+	case LoginStateUUID:
+		return "UUID"
+	case LoginStateScanned:
+		return "Scanned"
+	case LoginStateConfirmed:
+		return "Confirmed"
+	case LoginStateOnline:
+		return "Online"
+	case LoginStateOffline:
+		return "Offline"
+	}
+	return "Unknown"
+}
+
+// LoginEvent 登录状态变化事件
+type LoginEvent struct {
+	State LoginState
+	UUID  string
+	Body  []byte // 扫码和确认登录时的原始返回
+	Err   error  // 退出的原因
+}
+
+func (e *LoginEvent) Type() EventType { return EventLogin }
+
+// SyncCheckEvent 心跳事件
+type SyncCheckEvent struct {
+	Response SyncCheckResponse
+}
+
+func (e *SyncCheckEvent) Type() EventType { return EventSyncCheck }
+
+// MessageEvent 新消息事件
+type MessageEvent struct {
+	Message *Message
+}
+
+func (e *MessageEvent) Type() EventType { return EventMessage }
+
+// ContactModifiedEvent 联系人变更事件, 来自 webwxsync 的 ModContactList
+type ContactModifiedEvent struct {
+	Users []*User
+}
+
+func (e *ContactModifiedEvent) Type() EventType { return EventContactModified }
+
+// ContactDeletedEvent 联系人删除事件, 来自 webwxsync 的 DelContactList
+type ContactDeletedEvent struct {
+	Users []*User
+}
+
+func (e *ContactDeletedEvent) Type() EventType { return EventContactDeleted }
+
+// RecallEvent 消息撤回事件, Revoke 为解析失败时为nil
+type RecallEvent struct {
+	Message *Message
+	Revoke  *RevokeMsg
+}
+
+func (e *RecallEvent) Type() EventType { return EventMessageRecalled }
+
+// FriendRequestEvent 好友请求事件
+type FriendRequestEvent struct {
+	Message *Message
+}
+
+func (e *FriendRequestEvent) Type() EventType { return EventFriendRequest }
+
+// ErrorEvent 消息同步发生错误的事件, Retry 表示是否继续同步
+type ErrorEvent struct {
+	Err   error
+	Retry bool
+}
+
+func (e *ErrorEvent) Type() EventType { return EventError }
+
+// MessageSentEvent 消息发送成功事件
+type MessageSentEvent struct {
+	Message *SentMessage
+}
+
+func (e *MessageSentEvent) Type() EventType { return EventMessageSent }
+
+// Backpressure 异步订阅者的缓冲区满了之后的处理策略
+type Backpressure int
+
+const (
+	BackpressureBlock Backpressure = iota // 阻塞发布者直到缓冲区有空位
+	BackpressureDrop                      // 丢弃新的事件, 并计入 Dropped
+)
+
+const defaultEventBuffer = 64
+
+// SubscribeOption 订阅选项
+type SubscribeOption struct {
+	Types        []EventType  // 订阅的事件类型, 为空则订阅全部
+	Async        bool         // 是否在单独的协程中按顺序处理
+	Buffer       int          // 异步订阅者的缓冲区大小, 默认64
+	Backpressure Backpressure // 缓冲区满了之后的处理策略
+}
+
+// Subscription 订阅, 通过 Unsubscribe 取消
+type Subscription struct {
+	bus     *EventBus
+	handler func(Event)
+	option  SubscribeOption
+	events  chan Event
+	done    chan struct{}
+	once    sync.Once
+	dropped uint64
+}
+
+func (s *Subscription) match(e Event) bool {
+	if len(s.option.Types) == 0 {
+		return true
+	}
+	for _, t := range s.option.Types {
+		if t == e.Type() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subscription) handle(e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("event %s handler panic: %v", e.Type(), r)
+		}
+	}()
+	s.handler(e)
+}
+
+func (s *Subscription) deliver(e Event) {
+	if !s.option.Async {
+		s.handle(e)
+		return
+	}
+	if s.option.Backpressure == BackpressureDrop {
+		select {
+		case s.events <- e:
+		case <-s.done:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+		return
+	}
+	select {
+	case s.events <- e:
+	case <-s.done:
+	}
+}
+
+func (s *Subscription) run() {
+	for {
+		select {
+		case e := <-s.events:
+			s.handle(e)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Dropped 返回因为缓冲区已满而丢弃的事件数
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Unsubscribe 取消订阅, 异步订阅者缓冲区中未处理的事件会被丢弃
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		close(s.done)
+		s.bus.remove(s)
+	})
+}
+
+// EventBus 事件总线, 支持多个订阅者
+// 同步订阅者在发布者的协程中依次调用, 异步订阅者各自在单独的协程中按发布顺序处理
+type EventBus struct {
+	mu            sync.RWMutex
+	subscriptions []*Subscription
+}
+
+// NewEventBus Constructor
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe 订阅事件
+func (b *EventBus) Subscribe(handler func(Event), option SubscribeOption) *Subscription {
+	if handler == nil {
+		panic("event handler can not be nil")
+	}
+	s := &Subscription{bus: b, handler: handler, option: option, done: make(chan struct{})}
+	if option.Async {
+		if option.Buffer <= 0 {
+			option.Buffer = defaultEventBuffer
+		}
+		s.events = make(chan Event, option.Buffer)
+		go s.run()
+	}
+	b.mu.Lock()
+	b.subscriptions = append(b.subscriptions, s)
+	b.mu.Unlock()
+	return s
+}
+
+// On 同步订阅指定类型的事件
+func (b *EventBus) On(handler func(Event), types ...EventType) *Subscription {
+	return b.Subscribe(handler, SubscribeOption{Types: types})
+}
+
+func (b *EventBus) remove(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, sub := range b.subscriptions {
+		if sub == s {
+			b.subscriptions = append(b.subscriptions[:i:i], b.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+// Publish 发布事件给所有匹配的订阅者
+func (b *EventBus) Publish(e Event) {
+	b.mu.RLock()
+	subscriptions := b.subscriptions
+	b.mu.RUnlock()
+	for _, s := range subscriptions {
+		if s.match(e) {
+			s.deliver(e)
+		}
+	}
+}
+
+// Close 取消所有的订阅
+func (b *EventBus) Close() {
+	b.mu.RLock()
+	subscriptions := b.subscriptions
+	b.mu.RUnlock()
+	for _, s := range subscriptions {
+		s.Unsubscribe()
+	}
+}
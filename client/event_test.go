@@ -0,0 +1,115 @@
+package client
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitClosed(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for %s", what)
+	}
+}
+
+func TestEventBusTypesAndPanic(t *testing.T) {
+	bus := NewEventBus()
+	var got []EventType
+	bus.On(func(e Event) { panic("broken subscriber") }, EventError)
+	bus.On(func(e Event) { got = append(got, e.Type()) }, EventError, EventLogin)
+	bus.Publish(&ErrorEvent{})
+	bus.Publish(&SyncCheckEvent{})
+	bus.Publish(&LoginEvent{State: LoginStateOnline})
+	if len(got) != 2 || got[0] != EventError || got[1] != EventLogin {
+		t.Errorf("got %v, want Error and Login after the panic of another subscriber", got)
+	}
+}
+
+func TestEventBusAsyncOrder(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+	var mu sync.Mutex
+	var states []LoginState
+	done := make(chan struct{})
+	bus.Subscribe(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, e.(*LoginEvent).State)
+		if len(states) == 5 {
+			close(done)
+		}
+	}, SubscribeOption{Async: true, Buffer: 2})
+	for state := LoginStateUUID; state <= LoginStateOffline; state++ {
+		bus.Publish(&LoginEvent{State: state})
+	}
+	waitClosed(t, done, "async events")
+	mu.Lock()
+	defer mu.Unlock()
+	for i, state := range states {
+		if state != LoginStateUUID+LoginState(i) {
+			t.Fatalf("states = %v, want publish order", states)
+		}
+	}
+}
+
+// blockingSubscriber subscribes a handler that blocks until release is closed.
+func blockingSubscriber(bus *EventBus, backpressure Backpressure) (sub *Subscription, started, release chan struct{}) {
+	started = make(chan struct{}, 16)
+	release = make(chan struct{})
+	sub = bus.Subscribe(func(e Event) {
+		started <- struct{}{}
+		<-release
+	}, SubscribeOption{Async: true, Buffer: 1, Backpressure: backpressure})
+	return sub, started, release
+}
+
+func TestEventBusDrop(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+	sub, started, release := blockingSubscriber(bus, BackpressureDrop)
+	bus.Publish(&ErrorEvent{})
+	<-started
+	// one event waits in the buffer, the rest are dropped without blocking
+	for i := 0; i < 4; i++ {
+		bus.Publish(&ErrorEvent{})
+	}
+	if n := sub.Dropped(); n != 3 {
+		t.Errorf("Dropped = %d, want 3", n)
+	}
+	close(release)
+	<-started
+}
+
+func TestEventBusBlockAndUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+	sub, started, release := blockingSubscriber(bus, BackpressureBlock)
+	defer close(release)
+	bus.Publish(&ErrorEvent{})
+	<-started
+	bus.Publish(&ErrorEvent{})
+
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(&ErrorEvent{})
+		close(published)
+	}()
+	select {
+	case <-published:
+		t.Fatal("Publish should block while the buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	// unsubscribing releases the blocked publisher
+	sub.Unsubscribe()
+	waitClosed(t, published, "blocked publisher")
+	sub.Unsubscribe()
+
+	var calls int
+	bus.On(func(e Event) { calls++ })
+	bus.Publish(&ErrorEvent{})
+	if calls != 1 || sub.Dropped() != 0 {
+		t.Errorf("calls = %d, dropped = %d, want only the new subscriber called", calls, sub.Dropped())
+	}
+}
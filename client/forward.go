@@ -0,0 +1,159 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// 从App消息的内容中提取appmsg节点, 用于转发
+func extractAppMsg(content string) (string, error) {
+	start := strings.Index(content, "<appmsg")
+	end := strings.LastIndex(content, "</appmsg>")
+	if start < 0 || end < start {
+		return "", errors.New("appmsg not found")
+	}
+	return content[start : end+len("</appmsg>")], nil
+}
+
+// 将消息的文件下载到临时文件, 用于重新上传
+func (m *Message) saveFileToTemp() (*os.File, error) {
+	resp, err := m.GetFile()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var ext string
+	switch {
+	case m.IsPicture():
+		ext = ".jpg"
+	case m.IsSticker():
+		ext = ".gif"
+	case m.IsVideo():
+		ext = ".mp4"
+	}
+	file, err := os.CreateTemp("", "wx-forward-*"+ext)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(file, resp.Body); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+// 转发收到的媒体消息
+// 优先使用MediaId转发, 没有MediaId时下载后重新上传, 之后的联系人复用上传得到的MediaId
+func (s *Self) forwardMediaMessage(msg *Message, msgType MessageType, mediaId *string, user *User) (*SentMessage, error) {
+	info := s.Bot.Storage.LoginInfo
+	req := s.Bot.Storage.Request
+	if *mediaId != "" {
+		sendMsg := NewMediaSendMessage(msgType, s.UserName, user.UserName, *mediaId)
+		if msgType == MsgTypeVideo {
+			resp, err := s.Bot.Caller.Client.WebWxSendVideoMsg(req, sendMsg)
+			return s.sendMessageWrapper(getSuccessSentMessage(sendMsg, resp, err))
+		}
+		resp, err := s.Bot.Caller.Client.WebWxSendMsgImg(sendMsg, req, info)
+		return s.sendMessageWrapper(getSuccessSentMessage(sendMsg, resp, err))
+	}
+	file, err := msg.saveFileToTemp()
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	var sentMessage *SentMessage
+	if msgType == MsgTypeVideo {
+		sentMessage, err = s.sendVideoToUser(user, file)
+	} else {
+		sentMessage, err = s.sendImageToUser(user, file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	*mediaId = sentMessage.MediaId
+	return sentMessage, nil
+}
+
+// 转发收到的App消息, 复用原有的appmsg xml
+func (s *Self) forwardAppMessage(msg *Message, user *User) (*SentMessage, error) {
+	content, err := extractAppMsg(msg.Content)
+	if err != nil {
+		return nil, err
+	}
+	// 收到的文件消息的附件id在MediaId上
+	if msg.MediaId != "" {
+		content = strings.Replace(content, "<attachid></attachid>", "<attachid>"+msg.MediaId+"</attachid>", 1)
+	}
+	sendMsg := NewSendMessage(AppMessage, content, s.UserName, user.UserName, "")
+	return s.sendMessageWrapper(s.Bot.Caller.WebWxSendAppMsg(sendMsg, s.Bot.Storage.Request))
+}
+
+// 转发收到的消息给单个联系人, mediaId 在多个联系人之间共享
+func (s *Self) forwardReceivedMessage(msg *Message, mediaId *string, user *User) (*SentMessage, error) {
+	switch {
+	case msg.IsText():
+		return s.sendTextToUser(user, msg.Content)
+	case msg.IsPicture(), msg.IsSticker():
+		return s.forwardMediaMessage(msg, MsgTypeImage, mediaId, user)
+	case msg.IsVideo():
+		return s.forwardMediaMessage(msg, MsgTypeVideo, mediaId, user)
+	case msg.IsMedia():
+		return s.forwardAppMessage(msg, user)
+	}
+	return nil, errors.New("unsupport message")
+}
+
+// ForwardReceivedMessage 转发收到的消息给多个联系人(好友、群组)
+// 文本消息直接重新发送, 图片和视频通过MediaId转发或重新上传, App消息复用原有的appmsg xml
+// 遇到错误时立即返回已经转发成功的消息
+//      sentMessages, err := self.ForwardReceivedMessage(msg, friend.User, group.User)
+func (s *Self) ForwardReceivedMessage(msg *Message, users ...*User) ([]*SentMessage, error) {
+	sentMessages := make([]*SentMessage, 0, len(users))
+	mediaId := msg.MediaId
+	for _, user := range users {
+		sentMessage, err := s.forwardReceivedMessage(msg, &mediaId, user)
+		if err != nil {
+			return sentMessages, err
+		}
+		sentMessages = append(sentMessages, sentMessage)
+	}
+	return sentMessages, nil
+}
+
+// ForwardReceivedMessageToEach 转发收到的消息给多个联系人(好友、群组), 某个联系人失败时继续转发给其他联系人
+// 返回的消息和错误都按users的顺序排列, 媒体只会上传一次
+//      sentMessages, errs := self.ForwardReceivedMessageToEach(msg, friend.User, group.User)
+func (s *Self) ForwardReceivedMessageToEach(msg *Message, users ...*User) ([]*SentMessage, []error) {
+	sentMessages := make([]*SentMessage, len(users))
+	errs := make([]error, len(users))
+	mediaId := msg.MediaId
+	for i, user := range users {
+		sentMessages[i], errs[i] = s.forwardReceivedMessage(msg, &mediaId, user)
+	}
+	return sentMessages, errs
+}
+
+// ForwardToFriends 转发该消息给好友
+func (m *Message) ForwardToFriends(friends ...*Friend) ([]*SentMessage, error) {
+	var users = make([]*User, len(friends))
+	for index, friend := range friends {
+		users[index] = friend.User
+	}
+	return m.Bot.self.ForwardReceivedMessage(m, users...)
+}
+
+// ForwardToGroups 转发该消息给群组
+func (m *Message) ForwardToGroups(groups ...*Group) ([]*SentMessage, error) {
+	var users = make([]*User, len(groups))
+	for index, group := range groups {
+		users[index] = group.User
+	}
+	return m.Bot.self.ForwardReceivedMessage(m, users...)
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// hostRewriter sends every request to one server, uploads go to the file host.
+type hostRewriter struct {
+	host      string
+	transport http.RoundTripper
+}
+
+func (r hostRewriter) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Host = r.host
+	return r.transport.RoundTrip(req)
+}
+
+type forwardServer struct {
+	mu      sync.Mutex
+	uploads int
+	sent    map[string]string // ToUserName -> MediaId
+}
+
+// newForwardServer serves a picture, uploads and image messages,
+// sending to failUserName gets an error response.
+func newForwardServer(t *testing.T, failUserName string) (*Self, *forwardServer, func()) {
+	f := &forwardServer{sent: make(map[string]string)}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch r.URL.Path {
+		case webwxgetmsgimg:
+			w.Write([]byte("\x89PNG\r\n\x1a\n picture"))
+		case webwxuploadmedia:
+			f.uploads++
+			json.NewEncoder(w).Encode(map[string]interface{}{"BaseResponse": BaseResponse{}, "MediaId": "uploaded"})
+		case webwxsendmsgimg:
+			var body struct{ Msg SendMessage }
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decode request: %v", err)
+			}
+			if body.Msg.ToUserName == failUserName {
+				json.NewEncoder(w).Encode(map[string]interface{}{"BaseResponse": BaseResponse{Ret: 1}})
+				return
+			}
+			f.sent[body.Msg.ToUserName] = body.Msg.MediaId
+			json.NewEncoder(w).Encode(map[string]interface{}{"BaseResponse": BaseResponse{}, "MsgID": "1", "LocalID": "1"})
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	client := DefaultClient()
+	client.Client = server.Client()
+	client.Jar, _ = cookiejar.New(nil)
+	client.Transport = hostRewriter{host: strings.TrimPrefix(server.URL, "https://"), transport: client.Transport}
+	client.Domain = "wx.qq.com"
+	bot := &Bot{Caller: NewCaller(client), Events: NewEventBus(), Storage: &Storage{Request: &BaseRequest{}, LoginInfo: &LoginInfo{}}}
+	s := newTestSelf(2)
+	s.Bot = bot
+	bot.self = s
+	return s, f, server.Close
+}
+
+func TestForwardReceivedMessageToEach(t *testing.T) {
+	s, f, stop := newForwardServer(t, "@bad")
+	defer stop()
+	msg := &Message{MsgId: "100", MsgType: MsgTypeImage, Bot: s.Bot}
+	users := []*User{{UserName: "@f0"}, {UserName: "@bad"}, {UserName: "@f1"}}
+	var published []string
+	s.Bot.Events.On(func(e Event) {
+		published = append(published, e.(*MessageSentEvent).Message.ToUserName)
+	}, EventMessageSent)
+
+	sentMessages, errs := s.ForwardReceivedMessageToEach(msg, users...)
+	if errs[0] != nil || errs[2] != nil || errs[1] == nil {
+		t.Fatalf("errs = %v, want only @bad failed", errs)
+	}
+	if sentMessages[1] != nil || sentMessages[2] == nil || sentMessages[2].ToUserName != "@f1" {
+		t.Errorf("sent messages not in the order of users")
+	}
+	if len(published) != 2 || published[0] != "@f0" || published[1] != "@f1" {
+		t.Errorf("published sent events for %v, want @f0 and @f1", published)
+	}
+	// the picture is uploaded for the first user, the others reuse its MediaId
+	if f.uploads != 1 {
+		t.Errorf("uploaded %d times, want once", f.uploads)
+	}
+	if f.sent["@f0"] != "uploaded" || f.sent["@f1"] != "uploaded" {
+		t.Errorf("sent media ids %v, want the uploaded one for both", f.sent)
+	}
+}
+
+func TestForwardReceivedMessageStopsAtError(t *testing.T) {
+	s, f, stop := newForwardServer(t, "@bad")
+	defer stop()
+	// a received MediaId is forwarded as it is, nothing to upload
+	msg := &Message{MsgId: "100", MsgType: MsgTypeImage, MediaId: "received", Bot: s.Bot}
+
+	sentMessages, err := s.ForwardReceivedMessage(msg, &User{UserName: "@f0"}, &User{UserName: "@bad"}, &User{UserName: "@f1"})
+	if err == nil {
+		t.Fatal("want the error of @bad")
+	}
+	if len(sentMessages) != 1 || sentMessages[0].ToUserName != "@f0" {
+		t.Errorf("sent %v, want only @f0 before the error", sentMessages)
+	}
+	if f.uploads != 0 || f.sent["@f0"] != "received" || f.sent["@f1"] != "" {
+		t.Errorf("uploads %d, sent %v, want @f0 sent the received MediaId", f.uploads, f.sent)
+	}
+}
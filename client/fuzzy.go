@@ -0,0 +1,126 @@
+package client
+
+import (
+	"sort"
+	"strings"
+)
+
+// 模糊匹配的得分, 越高越匹配
+const (
+	fuzzyScoreExact       = 100
+	fuzzyScorePrefix      = 80
+	fuzzyScoreContains    = 60
+	fuzzyScoreSubsequence = 40
+)
+
+// 判断keyword的每个字符是否按顺序出现在s中
+func isSubsequence(keyword, s string) bool {
+	rs := []rune(s)
+	i := 0
+	for _, r := range keyword {
+		for i < len(rs) && rs[i] != r {
+			i++
+		}
+		if i == len(rs) {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// fuzzyScore 计算keyword与单个字段的匹配得分, 不区分大小写
+func fuzzyScore(keyword, field string) int {
+	if keyword == "" || field == "" {
+		return 0
+	}
+	field = strings.ToLower(field)
+	switch {
+	case field == keyword:
+		return fuzzyScoreExact
+	case strings.HasPrefix(field, keyword):
+		return fuzzyScorePrefix
+	case strings.Contains(field, keyword):
+		return fuzzyScoreContains
+	case isSubsequence(keyword, field):
+		return fuzzyScoreSubsequence
+	}
+	return 0
+}
+
+// FuzzyScore 计算keyword与用户的匹配得分
+// 匹配备注、昵称、群昵称、微信号以及它们的拼音首字母和全拼, 取最高分
+// 备注相关的字段优先于昵称
+//      user.FuzzyScore("zs")     // 匹配 张三(PYInitial: ZS)
+//      user.FuzzyScore("zhangs") // 匹配 张三(PYQuanPin: zhangsan)
+func (u *User) FuzzyScore(keyword string) int {
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	fields := []struct {
+		value string
+		bonus int
+	}{
+		{u.RemarkName, 5},
+		{u.RemarkPYInitial, 4},
+		{u.RemarkPYQuanPin, 4},
+		{u.NickName, 3},
+		{u.DisplayName, 3},
+		{u.PYInitial, 2},
+		{u.PYQuanPin, 2},
+		{u.Alias, 1},
+	}
+	var best int
+	for _, field := range fields {
+		score := fuzzyScore(keyword, field.value)
+		if score == 0 {
+			continue
+		}
+		if score += field.bonus; score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// FuzzySearch 根据关键字模糊查找, 结果按匹配得分从高到低排序
+// limit 小于等于0时返回全部匹配结果
+func (m Members) FuzzySearch(limit int, keyword string) Members {
+	type scored struct {
+		user  *User
+		score int
+	}
+	var results []scored
+	for _, member := range m {
+		if score := member.FuzzyScore(keyword); score > 0 {
+			results = append(results, scored{user: member, score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	members := make(Members, len(results))
+	for i, result := range results {
+		members[i] = result.user
+	}
+	return members
+}
+
+// FuzzySearch 根据关键字模糊查找好友, 结果按匹配得分从高到低排序
+func (f Friends) FuzzySearch(limit int, keyword string) Friends {
+	members := make(Members, len(f))
+	for i, friend := range f {
+		members[i] = friend.User
+	}
+	return members.FuzzySearch(limit, keyword).Friends()
+}
+
+// FuzzySearch 根据关键字模糊查找群组, 结果按匹配得分从高到低排序
+func (g Groups) FuzzySearch(limit int, keyword string) Groups {
+	members := make(Members, len(g))
+	for i, group := range g {
+		members[i] = group.User
+	}
+	return members.FuzzySearch(limit, keyword).Groups()
+}
@@ -0,0 +1,58 @@
+package client
+
+import "testing"
+
+func TestFuzzyScore(t *testing.T) {
+	zhang := &User{NickName: "张三", PYInitial: "ZS", PYQuanPin: "zhangsan", RemarkName: "Boss", Alias: "zs_2020"}
+	cases := []struct {
+		keyword string
+		want    int
+	}{
+		{"boss", fuzzyScoreExact + 5},       // remark, case insensitive
+		{"bo", fuzzyScorePrefix + 5},        // remark prefix
+		{"张三", fuzzyScoreExact + 3},         // nick name
+		{"zs", fuzzyScoreExact + 2},         // pinyin initials
+		{"zhangs", fuzzyScorePrefix + 2},    // full pinyin prefix
+		{"san", fuzzyScoreContains + 2},     // full pinyin contains
+		{"zgsn", fuzzyScoreSubsequence + 2}, // full pinyin subsequence
+		{"2020", fuzzyScoreContains + 1},    // alias
+		{"  ZS ", fuzzyScoreExact + 2},      // trimmed
+		{"lisi", 0},
+		{"", 0},
+	}
+	for _, c := range cases {
+		if got := zhang.FuzzyScore(c.keyword); got != c.want {
+			t.Errorf("FuzzyScore(%q) = %d, want %d", c.keyword, got, c.want)
+		}
+	}
+	remarked := &User{NickName: "x", RemarkName: "李四", RemarkPYInitial: "LS", RemarkPYQuanPin: "lisi"}
+	if got := remarked.FuzzyScore("ls"); got != fuzzyScoreExact+4 {
+		t.Errorf("remark pinyin initials = %d, want %d", got, fuzzyScoreExact+4)
+	}
+}
+
+func TestFuzzySearchRanking(t *testing.T) {
+	members := Members{
+		{UserName: "@sub", NickName: "a-l-i"},
+		{UserName: "@contains", NickName: "malia"},
+		{UserName: "@prefix", NickName: "alice"},
+		{UserName: "@exact", NickName: "ali"},
+		{UserName: "@remark", NickName: "x", RemarkName: "alison"},
+		{UserName: "@tie", NickName: "alibaba"},
+		{UserName: "@none", NickName: "bob"},
+	}
+	want := []string{"@exact", "@remark", "@prefix", "@tie", "@contains", "@sub"}
+	results := members.FuzzySearch(0, "ali")
+	if results.Count() != len(want) {
+		t.Fatalf("got %d results, want %d", results.Count(), len(want))
+	}
+	// a remark beats a nick name with the same kind of match, ties keep their order
+	for i, user := range results {
+		if user.UserName != want[i] {
+			t.Errorf("results[%d] = %s, want %s", i, user.UserName, want[i])
+		}
+	}
+	if limited := members.FuzzySearch(2, "ali"); limited.Count() != 2 || limited[1].UserName != "@remark" {
+		t.Errorf("FuzzySearch(2) = %v", limited)
+	}
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"strings"
+)
+
+// 微信群消息中@成员的分隔符
+const mentionSeparator = "\u2005"
+
+// @所有人 的几种写法
+var mentionAllNames = []string{"所有人", "All", "all"}
+
+// Mention 群消息中被@的成员
+type Mention struct {
+	User *User  // 被@的群成员, @所有人 时为nil
+	Name string // 消息中@后面的名称
+	All  bool   // 是否为@所有人
+}
+
+// Mentions 群消息中被@的成员列表
+type Mentions []*Mention
+
+// Count 统计数量
+func (m Mentions) Count() int {
+	return len(m)
+}
+
+// HasAll 判断是否@了所有人
+func (m Mentions) HasAll() bool {
+	for _, mention := range m {
+		if mention.All {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains 判断是否@了指定的群成员
+func (m Mentions) Contains(username string) bool {
+	for _, mention := range m {
+		if mention.User != nil && mention.User.UserName == username {
+			return true
+		}
+	}
+	return false
+}
+
+// Users 获取被@的所有群成员, 不包括@所有人
+func (m Mentions) Users() Members {
+	var members Members
+	for _, mention := range m {
+		if mention.User != nil {
+			members = append(members, mention.User)
+		}
+	}
+	return members
+}
+
+// 获取群成员在群聊中被@时使用的名称
+func mentionName(user *User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.NickName
+}
+
+// 判断content中name后面是否为@的结束位置
+func isMentionEnd(rest string) bool {
+	return rest == "" || strings.HasPrefix(rest, mentionSeparator) || strings.HasPrefix(rest, " ")
+}
+
+// parseMentions 根据群成员列表解析消息中被@的成员
+// 同一位置优先匹配最长的名称, 避免成员名称互为前缀时匹配错误
+func parseMentions(content string, members Members) Mentions {
+	var mentions Mentions
+	for i := 0; i < len(content); i++ {
+		if content[i] != '@' {
+			continue
+		}
+		rest := content[i+1:]
+		var mention *Mention
+		for _, name := range mentionAllNames {
+			if strings.HasPrefix(rest, name) && isMentionEnd(rest[len(name):]) {
+				mention = &Mention{Name: name, All: true}
+				break
+			}
+		}
+		for _, member := range members {
+			name := mentionName(member)
+			if name == "" || !strings.HasPrefix(rest, name) || !isMentionEnd(rest[len(name):]) {
+				continue
+			}
+			if mention == nil || len(name) > len(mention.Name) {
+				mention = &Mention{User: member, Name: name}
+			}
+		}
+		if mention == nil {
+			continue
+		}
+		duplicated := mention.All && mentions.HasAll() || mention.User != nil && mentions.Contains(mention.User.UserName)
+		if !duplicated {
+			mentions = append(mentions, mention)
+		}
+		i += len(mention.Name)
+	}
+	return mentions
+}
+
+// MentionText 构造@群成员的文本消息内容
+//
+//	text := MentionText("hello", member1, member2) // "@member1\u2005@member2\u2005hello"
+func MentionText(text string, members ...*User) string {
+	var builder strings.Builder
+	for _, member := range members {
+		builder.WriteString("@")
+		builder.WriteString(mentionName(member))
+		builder.WriteString(mentionSeparator)
+	}
+	builder.WriteString(text)
+	return builder.String()
+}
+
+// MentionAllText 构造@所有人的文本消息内容, 只有群主或群管理员发送才有效
+func MentionAllText(text string) string {
+	return "@" + mentionAllNames[0] + mentionSeparator + text
+}
@@ -0,0 +1,78 @@
+package client
+
+import "testing"
+
+func TestParseMentions(t *testing.T) {
+	members := Members{
+		{UserName: "@a", NickName: "alice"},
+		{UserName: "@ab", NickName: "alice bob"},
+		{UserName: "@c", NickName: "carol", DisplayName: "Carol in group"},
+	}
+	cases := []struct {
+		content string
+		want    []string // UserName of each mention, "all" for @all
+	}{
+		{"@alice\u2005hi", []string{"@a"}},
+		{"@alice bob\u2005hi", []string{"@ab"}},                           // the longest name wins
+		{"@alice bobby hi", []string{"@a"}},                               // a name must end at a separator
+		{"@Carol in group hi @carol hi", []string{"@c"}},                  // display name only
+		{"@alice\u2005@alice\u2005@所有人\u2005@all", []string{"@a", "all"}}, // duplicates once
+		{"@All", []string{"all"}},
+		{"mail@alice.com", nil},
+		{"no mention", nil},
+	}
+	for _, c := range cases {
+		mentions := parseMentions(c.content, members)
+		var got []string
+		for _, mention := range mentions {
+			if mention.All {
+				got = append(got, "all")
+			} else {
+				got = append(got, mention.User.UserName)
+			}
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("parseMentions(%q) = %v, want %v", c.content, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("parseMentions(%q) = %v, want %v", c.content, got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMentionText(t *testing.T) {
+	members := Members{{UserName: "@a", NickName: "alice"}, {UserName: "@b", NickName: "bob", DisplayName: "Bobby"}}
+	text := MentionText("hi", members...)
+	if text != "@alice\u2005@Bobby\u2005hi" {
+		t.Errorf("MentionText = %q", text)
+	}
+	if mentions := parseMentions(text, members); mentions.Count() != 2 {
+		t.Errorf("MentionText not parsed back: %v", mentions)
+	}
+	if text = MentionAllText("hi"); text != "@所有人\u2005hi" || !parseMentions(text, nil).HasAll() {
+		t.Errorf("MentionAllText = %q", text)
+	}
+}
+
+func TestInitMentionsUncachedGroup(t *testing.T) {
+	s := newTestSelf(0)
+	s.NickName = "me"
+	s.Bot = &Bot{self: s}
+	// the group is not cached, only @me and @all can be told without fetching it
+	msg := &Message{Bot: s.Bot, FromUserName: "@@g", ToUserName: "@me", Content: "@alice\u2005@me\u2005hi"}
+	msg.initMentions()
+	if !msg.IsAt() || msg.Mentions().Count() != 1 || !msg.Mentions().Contains("@me") {
+		t.Errorf("mentions = %v, want only me", msg.Mentions())
+	}
+
+	s.details.put(&User{UserName: "@@g", MemberList: Members{{UserName: "@a", NickName: "alice"}}})
+	msg = &Message{Bot: s.Bot, FromUserName: "@@g", ToUserName: "@me", Content: "@alice\u2005hi"}
+	msg.initMentions()
+	if msg.IsAt() || !msg.Mentions().Contains("@a") {
+		t.Errorf("mentions = %v, want alice from the cached group", msg.Mentions())
+	}
+}
@@ -21,8 +21,9 @@ var (
 )
 
 type Message struct {
-	isAt    bool
-	AppInfo struct {
+	isAt     bool
+	mentions Mentions
+	AppInfo  struct {
 		Type  int
 		AppID string
 	}
@@ -75,9 +76,11 @@ func (m *Message) Sender() (*User, error) {
 	if m.FromUserName == m.Bot.self.User.UserName {
 		return m.Bot.self.User, nil
 	}
-	user := &User{UserName: m.FromUserName}
-	err := user.Detail(m.Bot.self)
-	return user, err
+	user, err := m.Bot.self.UserDetail(m.FromUserName)
+	if err != nil {
+		return &User{UserName: m.FromUserName}, err
+	}
+	return user, nil
 }
 
 // SenderInGroup 获取消息在群里面的发送者
@@ -89,24 +92,36 @@ func (m *Message) SenderInGroup() (*User, error) {
 		}
 		return nil, ErrMsgIsFromSys
 	}
+	// Sender 返回的详情里已经带有群成员列表
 	group, err := m.Sender()
 	if err != nil {
 		return nil, err
 	}
-	if err := group.Detail(m.Bot.self); err != nil {
-		return nil, err
-	}
 	if group.IsFriend() {
 		return group, nil
 	}
 	users := group.MemberList.SearchByUserName(1, m.senderInGroupUserName)
 	if users == nil {
-		return nil, ErrNoSuchUserFoundError
+		// 缓存的群详情里可能还没有新入群的成员, 重新获取一次
+		if m.Bot.self.details == nil {
+			return nil, ErrNoSuchUserFoundError
+		}
+		m.Bot.self.details.invalidate(group.UserName)
+		if group, err = m.Sender(); err != nil {
+			return nil, err
+		}
+		if users = group.MemberList.SearchByUserName(1, m.senderInGroupUserName); users == nil {
+			return nil, ErrNoSuchUserFoundError
+		}
 	}
-	users.init()
 	return users.First(), nil
 }
 
+// SenderInGroupUserName 获取群消息发送者的UserName
+func (m *Message) SenderInGroupUserName() string {
+	return m.senderInGroupUserName
+}
+
 // Receiver 获取消息的接收者
 // 如果消息是群组消息，则返回群组
 // 如果消息是好友消息，则返回好友
@@ -140,9 +155,11 @@ func (m *Message) Receiver() (*User, error) {
 		return user, nil
 	}
 
-	user = &User{UserName: m.ToUserName}
-	err := user.Detail(m.Bot.self)
-	return user, err
+	user, err := m.Bot.self.UserDetail(m.ToUserName)
+	if err != nil {
+		return &User{UserName: m.ToUserName}, err
+	}
+	return user, nil
 }
 
 // IsSendBySelf 判断消息是否由自己发送
@@ -427,6 +444,35 @@ func (m *Message) initMessageCategory(sender *User, receiver *User) {
 	m.Category = CategoryFriend
 }
 
+// 根据群成员列表解析群消息中被@的成员
+// 在同步消息的协程中调用, 只使用已缓存的群成员列表, 不发起请求
+// 群成员列表未缓存时只能识别@自己和@所有人
+func (m *Message) initMentions() {
+	if !strings.Contains(m.Content, "@") {
+		return
+	}
+	groupUserName := m.FromUserName
+	if m.IsSendBySelf() {
+		groupUserName = m.ToUserName
+	}
+	self := m.Bot.self
+	var members Members
+	if group, ok := self.CachedUserDetail(groupUserName); ok {
+		members = group.MemberList
+	} else if group, ok := self.FindContactByUserName(groupUserName); ok {
+		members = group.MemberList
+	}
+	if _, ok := members.GetByUserName(self.UserName); !ok {
+		members = append(members[:len(members):len(members)], self.User)
+	}
+	m.mentions = parseMentions(m.Content, members)
+	if m.IsSendBySelf() {
+		m.isAt = m.mentions.Count() > 0
+	} else {
+		m.isAt = m.mentions.HasAll() || m.mentions.Contains(m.Bot.self.UserName)
+	}
+}
+
 // 消息初始化,根据不同的消息作出不同的处理
 func (m *Message) init(bot *Bot) {
 	m.Bot = bot
@@ -441,28 +487,6 @@ func (m *Message) init(bot *Bot) {
 				data := strings.Split(m.Content, ":<br/>")
 				m.Content = strings.Join(data[1:], "")
 				m.senderInGroupUserName = data[0]
-				if strings.Contains(m.Content, "@") {
-					sender, err := m.Sender()
-					if err == nil {
-						receiver := sender.MemberList.SearchByUserName(1, m.ToUserName)
-						if receiver != nil {
-							displayName := receiver.First().DisplayName
-							if displayName == "" {
-								displayName = receiver.First().NickName
-							}
-							var atFlag string
-							if strings.Contains(m.Content, "\u2005") {
-								atFlag = "@" + displayName + "\u2005"
-							} else {
-								atFlag = "@" + displayName + " "
-							}
-							m.isAt = strings.Contains(m.Content, atFlag) || strings.HasSuffix(m.Content, atFlag)
-						}
-					}
-				}
-			} else {
-				// 这块不严谨，但是只能这么干了
-				m.isAt = strings.Contains(m.Content, "@") || strings.Contains(m.Content, "\u2005")
 			}
 		}
 	}
@@ -472,6 +496,12 @@ func (m *Message) init(bot *Bot) {
 	m.Content = html.UnescapeString(m.Content)
 	// 处理消息中的emoji表情
 	m.Content = FormatEmoji(m.Content)
+	// 解析群消息中被@的成员
+	if strings.HasPrefix(m.FromUserName, "@@") || strings.HasPrefix(m.ToUserName, "@@") {
+		if !m.IsSystem() {
+			m.initMentions()
+		}
+	}
 
 	m.initPersistence()
 }
@@ -620,14 +650,58 @@ func (s *SentMessage) Revoke() error {
 	return s.Self.RevokeMessage(s)
 }
 
-// CanRevoke 是否可以撤回该消息
-func (s *SentMessage) CanRevoke() bool {
+// 消息发送后可以撤回的时长
+const revokeWindow = time.Minute * 2
+
+// SentAt 消息的发送时间, 由ClientMsgId得出
+func (s *SentMessage) SentAt() time.Time {
 	i, err := strconv.ParseInt(s.ClientMsgId, 10, 64)
 	if err != nil {
-		return false
+		return time.Time{}
 	}
-	start := time.Unix(i/10000000, 0)
-	return time.Now().Sub(start) < time.Minute*2
+	return time.Unix(i/10000000, 0)
+}
+
+// RevokeRemaining 剩余的可撤回时长, 已经不能撤回时返回0
+func (s *SentMessage) RevokeRemaining() time.Duration {
+	sentAt := s.SentAt()
+	if sentAt.IsZero() {
+		return 0
+	}
+	remaining := revokeWindow - time.Now().Sub(sentAt)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// CanRevoke 是否可以撤回该消息
+func (s *SentMessage) CanRevoke() bool {
+	return s.RevokeRemaining() > 0
+}
+
+// Message 转换成自己发送的 Message, 用于和收到的消息保存在一起
+func (s *SentMessage) Message() *Message {
+	msg := &Message{
+		MsgType:      s.Type,
+		Content:      s.Content,
+		FromUserName: s.FromUserName,
+		ToUserName:   s.ToUserName,
+		MediaId:      s.MediaId,
+		MsgId:        s.MsgId,
+		CreateTime:   s.SentAt().Unix(),
+	}
+	msg.Category = CategoryFriend
+	if strings.HasPrefix(s.ToUserName, "@@") {
+		msg.Category = CategoryGroup
+	}
+	if s.Self != nil {
+		msg.Bot = s.Self.Bot
+		if user, ok := s.Self.FindContactByUserName(s.ToUserName); ok && user.IsMP() {
+			msg.Category = CategoryMP
+		}
+	}
+	return msg
 }
 
 // ForwardToFriends 转发该消息给好友
@@ -745,6 +819,12 @@ func (m *Message) String() string {
 }
 
 // IsAt 判断消息是否为@消息
+// 收到的消息为@了自己或@所有人, 自己发送的消息为@了任意群成员
 func (m *Message) IsAt() bool {
 	return m.isAt
 }
+
+// Mentions 获取群消息中被@的成员, 只包括收到消息时已缓存的群成员
+func (m *Message) Mentions() Mentions {
+	return m.mentions
+}
@@ -1,6 +1,11 @@
 package client
 
-import "strings"
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+)
 
 // MessageHandler 消息处理函数
 type MessageHandler func(msg *Message)
@@ -28,9 +33,27 @@ type MessageContext struct {
 	index           int
 	abortIndex      int
 	messageHandlers MessageContextHandlerGroup
+	handlerNames    []string
+	context         context.Context
 	*Message
 }
 
+// Context 返回当前处理函数的 context, 配合 TimeoutMiddleware 使用时带有超时
+func (c *MessageContext) Context() context.Context {
+	if c.context == nil {
+		return context.Background()
+	}
+	return c.context
+}
+
+// HandlerName 返回当前消息处理函数的名字, 用于日志和统计
+func (c *MessageContext) HandlerName() string {
+	if c.index > 0 && c.index <= len(c.handlerNames) {
+		return c.handlerNames[c.index-1]
+	}
+	return ""
+}
+
 // Next 主动调用下一个消息处理函数(或开始调用)
 func (c *MessageContext) Next() {
 	c.index++
@@ -78,6 +101,7 @@ func MatchFuncList(matchFuncs ...MatchFunc) MatchFunc {
 }
 
 type matchNode struct {
+	name      string
 	matchFunc MatchFunc
 	group     MessageContextHandlerGroup
 }
@@ -92,8 +116,13 @@ type matchNodes []*matchNode
 //		bot := DefaultBot()
 //		bot.MessageHandler = DispatchMessage(dispatcher)
 type MessageMatchDispatcher struct {
-	async      bool
-	matchNodes matchNodes
+	async       bool
+	workers     int
+	queueSize   int
+	mu          sync.Mutex
+	pool        *workerPool
+	middlewares []MessageMiddleware
+	matchNodes  matchNodes
 }
 
 // NewMessageMatchDispatcher Constructor
@@ -102,27 +131,86 @@ func NewMessageMatchDispatcher() *MessageMatchDispatcher {
 }
 
 // SetAsync 设置是否异步处理
+// 异步处理使用默认大小的协程池, 同一个会话的消息按顺序处理
 func (m *MessageMatchDispatcher) SetAsync(async bool) {
 	m.async = async
 }
 
+// SetWorkerPool 设置异步处理的协程数和每个协程的队列长度, 并开启异步处理
+// 队列满了之后 Dispatch 会阻塞, 需要在第一次 Dispatch 之前设置
+func (m *MessageMatchDispatcher) SetWorkerPool(workers, queueSize int) {
+	m.workers = workers
+	m.queueSize = queueSize
+	m.async = true
+}
+
+// Use 添加全局的中间件, 中间件包裹每一个消息处理函数, 先添加的在外层
+//		dispatcher.Use(RecoverMiddleware(nil), LoggingMiddleware(nil))
+func (m *MessageMatchDispatcher) Use(middlewares ...MessageMiddleware) {
+	m.middlewares = append(m.middlewares, middlewares...)
+}
+
 // Dispatch impl MessageDispatcher
 // 遍历 MessageMatchDispatcher 所有的消息处理函数
 // 获取所有匹配上的函数
 // 执行处理的消息处理方法
+// 异步处理时匹配也在协程池中进行
 func (m *MessageMatchDispatcher) Dispatch(msg *Message) {
+	if !m.async {
+		m.dispatch(msg)
+		return
+	}
+	m.mu.Lock()
+	if m.pool == nil {
+		m.pool = newWorkerPool(m.workers, m.queueSize, m.dispatch)
+	}
+	pool := m.pool
+	m.mu.Unlock()
+	if !pool.submit(msg) {
+		// 已经关闭, 直接处理
+		m.dispatch(msg)
+	}
+}
+
+// Close 关闭异步处理的协程池, 等待队列中的消息处理完成
+// 关闭之后的消息会同步处理
+func (m *MessageMatchDispatcher) Close() {
+	m.mu.Lock()
+	pool := m.pool
+	m.mu.Unlock()
+	if pool != nil {
+		pool.close()
+	}
+}
+
+func (m *MessageMatchDispatcher) dispatch(msg *Message) {
+	// 保证处理函数的panic不会中断消息同步的协程
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("dispatch message %s panic: %v", msg.MsgId, r)
+		}
+	}()
 	var group MessageContextHandlerGroup
+	var names []string
 	for _, node := range m.matchNodes {
 		if node.matchFunc(msg) {
-			group = append(group, node.group...)
+			for _, handler := range node.group {
+				group = append(group, m.wrap(handler))
+				names = append(names, node.name)
+			}
 		}
 	}
-	ctx := &MessageContext{Message: msg, messageHandlers: group}
-	if m.async {
-		go m.do(ctx)
-	} else {
-		m.do(ctx)
+	ctx := &MessageContext{Message: msg, messageHandlers: group, handlerNames: names}
+	m.do(ctx)
+}
+
+// 用中间件包裹处理函数
+func (m *MessageMatchDispatcher) wrap(handler MessageContextHandler) MessageContextHandler {
+	for i := len(m.middlewares) - 1; i >= 0; i-- {
+		middleware, next := m.middlewares[i], handler
+		handler = func(ctx *MessageContext) { middleware(ctx, next) }
 	}
+	return handler
 }
 
 func (m *MessageMatchDispatcher) do(ctx *MessageContext) {
@@ -132,10 +220,19 @@ func (m *MessageMatchDispatcher) do(ctx *MessageContext) {
 // RegisterHandler 注册消息处理函数, 根据自己的需求自定义
 // matchFunc返回true则表示处理对应的handlers
 func (m *MessageMatchDispatcher) RegisterHandler(matchFunc MatchFunc, handlers ...MessageContextHandler) {
+	var name string
+	if len(handlers) > 0 {
+		name = handlerName(handlers[0])
+	}
+	m.RegisterNamedHandler(name, matchFunc, handlers...)
+}
+
+// RegisterNamedHandler 注册带名字的消息处理函数, 名字用于中间件的日志和统计
+func (m *MessageMatchDispatcher) RegisterNamedHandler(name string, matchFunc MatchFunc, handlers ...MessageContextHandler) {
 	if matchFunc == nil {
 		panic("MatchFunc can not be nil")
 	}
-	node := &matchNode{matchFunc: matchFunc, group: handlers}
+	node := &matchNode{name: name, matchFunc: matchFunc, group: handlers}
 	m.matchNodes = append(m.matchNodes, node)
 }
 
@@ -193,7 +290,7 @@ func (m *MessageMatchDispatcher) OnFriend(handlers ...MessageContextHandler) {
 
 // OnGroup 注册发送者为群组的处理函数
 func (m *MessageMatchDispatcher) OnGroup(handlers ...MessageContextHandler) {
-	m.RegisterHandler(func(message *Message) bool { return message.Category == CategoryFriend }, handlers...)
+	m.RegisterHandler(func(message *Message) bool { return message.Category == CategoryGroup }, handlers...)
 }
 
 // OnUser 注册根据消息发送者的行为是否匹配的消息处理函数
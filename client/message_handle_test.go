@@ -0,0 +1,112 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolConversationOrder(t *testing.T) {
+	dispatcher := NewMessageMatchDispatcher()
+	dispatcher.SetWorkerPool(4, 2)
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	dispatcher.RegisterHandler(func(*Message) bool { return true }, func(ctx *MessageContext) {
+		n, _ := strconv.Atoi(ctx.Content)
+		if n%7 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		mu.Lock()
+		seen[ctx.FromUserName] = append(seen[ctx.FromUserName], n)
+		mu.Unlock()
+	})
+	const conversations, count = 5, 50
+	var wg sync.WaitGroup
+	for c := 0; c < conversations; c++ {
+		wg.Add(1)
+		go func(from string) {
+			defer wg.Done()
+			for n := 0; n < count; n++ {
+				dispatcher.Dispatch(&Message{FromUserName: from, Content: strconv.Itoa(n)})
+			}
+		}(fmt.Sprintf("@user%d", c))
+	}
+	wg.Wait()
+	dispatcher.Close()
+
+	mu.Lock()
+	if len(seen) != conversations {
+		t.Fatalf("got %d conversations, want %d", len(seen), conversations)
+	}
+	for from, ns := range seen {
+		if len(ns) != count {
+			t.Errorf("%s: handled %d messages, want %d", from, len(ns), count)
+		}
+		for i, n := range ns {
+			if n != i {
+				t.Errorf("%s: handled out of order: %v", from, ns)
+				break
+			}
+		}
+	}
+	mu.Unlock()
+
+	// after Close messages are handled synchronously
+	dispatcher.Dispatch(&Message{FromUserName: "@late", Content: "0"})
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen["@late"]) != 1 {
+		t.Error("message after Close not handled")
+	}
+}
+
+func TestMiddlewareRecoverAndTimeout(t *testing.T) {
+	metrics := NewDispatchMetrics()
+	var panics, timeouts int
+	dispatcher := NewMessageMatchDispatcher()
+	dispatcher.Use(
+		RecoverMiddleware(func(ctx *MessageContext, r interface{}) { panics++ }),
+		TimeoutMiddleware(10*time.Millisecond, func(ctx *MessageContext, elapsed time.Duration) { timeouts++ }),
+		metrics.Middleware(),
+	)
+	all := func(*Message) bool { return true }
+	var calls []string
+	dispatcher.RegisterNamedHandler("panic", all, func(ctx *MessageContext) {
+		calls = append(calls, ctx.HandlerName())
+		panic("boom")
+	})
+	dispatcher.RegisterNamedHandler("slow", all, func(ctx *MessageContext) {
+		calls = append(calls, ctx.HandlerName())
+		select {
+		case <-ctx.Context().Done():
+		case <-time.After(time.Second):
+			t.Error("the handler did not see the timeout")
+		}
+	})
+	dispatcher.RegisterNamedHandler("fast", all, func(ctx *MessageContext) {
+		calls = append(calls, ctx.HandlerName())
+		if err := ctx.Context().Err(); err != nil {
+			t.Errorf("fast handler got the context of another handler: %v", err)
+		}
+	})
+	dispatcher.Dispatch(&Message{MsgId: "1"})
+
+	if fmt.Sprint(calls) != "[panic slow fast]" {
+		t.Errorf("calls = %v, want every handler called once", calls)
+	}
+	if panics != 1 || timeouts != 1 {
+		t.Errorf("panics = %d, timeouts = %d, want 1 and 1", panics, timeouts)
+	}
+	stats := metrics.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("stats = %+v, want 3 handlers", stats)
+	}
+	if s := stats[1]; s.Name != "panic" || s.Calls != 1 || s.Panics != 1 {
+		t.Errorf("panic stats = %+v", s)
+	}
+	if s := stats[2]; s.Name != "slow" || s.Timeouts != 1 || s.Max < 10*time.Millisecond {
+		t.Errorf("slow stats = %+v", s)
+	}
+}
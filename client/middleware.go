@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MessageMiddleware 消息处理中间件, 调用next执行被包裹的处理函数
+//		func(ctx *MessageContext, next MessageContextHandler) {
+//			start := time.Now()
+//			next(ctx)
+//			log.Println(ctx.HandlerName(), time.Since(start))
+//		}
+type MessageMiddleware func(ctx *MessageContext, next MessageContextHandler)
+
+func handlerName(handler MessageContextHandler) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
+
+// RecoverMiddleware 捕获处理函数的panic, 之后的处理函数继续执行
+// onPanic 为nil时打印日志
+func RecoverMiddleware(onPanic func(ctx *MessageContext, r interface{})) MessageMiddleware {
+	return func(ctx *MessageContext, next MessageContextHandler) {
+		defer func() {
+			if r := recover(); r != nil {
+				if onPanic != nil {
+					onPanic(ctx, r)
+				} else {
+					log.Printf("message handler %s panic: %v", ctx.HandlerName(), r)
+				}
+			}
+		}()
+		next(ctx)
+	}
+}
+
+// LoggingMiddleware 打印每个处理函数处理的消息和耗时, logger 为nil时使用默认的logger
+func LoggingMiddleware(logger *log.Logger) MessageMiddleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(ctx *MessageContext, next MessageContextHandler) {
+		start := time.Now()
+		next(ctx)
+		logger.Printf("message %s handled by %s in %s", ctx.MsgId, ctx.HandlerName(), time.Since(start))
+	}
+}
+
+// TimeoutMiddleware 给处理函数设置超时, 处理函数需要通过 ctx.Context() 感知超时并尽快返回
+// 超时返回之后调用 onTimeout, onTimeout 为nil时打印日志
+func TimeoutMiddleware(timeout time.Duration, onTimeout func(ctx *MessageContext, elapsed time.Duration)) MessageMiddleware {
+	return func(ctx *MessageContext, next MessageContextHandler) {
+		parent := ctx.context
+		c, cancel := context.WithTimeout(ctx.Context(), timeout)
+		defer cancel()
+		ctx.context = c
+		// 处理函数panic时也要恢复, 否则之后的处理函数拿到的是已经取消的 context
+		defer func() { ctx.context = parent }()
+		start := time.Now()
+		next(ctx)
+		if c.Err() != context.DeadlineExceeded {
+			return
+		}
+		if onTimeout != nil {
+			onTimeout(ctx, time.Since(start))
+		} else {
+			log.Printf("message handler %s timed out after %s", ctx.HandlerName(), time.Since(start))
+		}
+	}
+}
+
+// HandlerStats 处理函数的统计
+type HandlerStats struct {
+	Name     string
+	Calls    uint64
+	Panics   uint64
+	Timeouts uint64
+	Total    time.Duration
+	Max      time.Duration
+}
+
+// Average 平均耗时
+func (s HandlerStats) Average() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+// DispatchMetrics 按处理函数名字统计调用次数, panic, 超时和耗时
+//		metrics := NewDispatchMetrics()
+//		dispatcher.Use(RecoverMiddleware(nil), TimeoutMiddleware(time.Minute, nil), metrics.Middleware())
+type DispatchMetrics struct {
+	mu       sync.Mutex
+	handlers map[string]*HandlerStats
+}
+
+// NewDispatchMetrics Constructor
+func NewDispatchMetrics() *DispatchMetrics {
+	return &DispatchMetrics{handlers: make(map[string]*HandlerStats)}
+}
+
+func (d *DispatchMetrics) observe(name string, elapsed time.Duration, panicked, timedOut bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats, ok := d.handlers[name]
+	if !ok {
+		stats = &HandlerStats{Name: name}
+		d.handlers[name] = stats
+	}
+	stats.Calls++
+	stats.Total += elapsed
+	if elapsed > stats.Max {
+		stats.Max = elapsed
+	}
+	if panicked {
+		stats.Panics++
+	}
+	if timedOut {
+		stats.Timeouts++
+	}
+}
+
+// Middleware 返回记录统计的中间件, 需要放在 RecoverMiddleware 和 TimeoutMiddleware 之后
+func (d *DispatchMetrics) Middleware() MessageMiddleware {
+	return func(ctx *MessageContext, next MessageContextHandler) {
+		start := time.Now()
+		completed := false
+		defer func() {
+			timedOut := ctx.Context().Err() == context.DeadlineExceeded
+			d.observe(ctx.HandlerName(), time.Since(start), !completed, timedOut)
+		}()
+		next(ctx)
+		completed = true
+	}
+}
+
+// Stats 返回按名字排序的统计
+func (d *DispatchMetrics) Stats() []HandlerStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := make([]HandlerStats, 0, len(d.handlers))
+	for _, s := range d.handlers {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
@@ -1,5 +1,11 @@
 package client
 
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
 //// mode 类型限制
 //type mode string
 //
@@ -41,6 +47,26 @@ const (
 	qrcode            = "https://login.weixin.qq.com/qrcode/"
 )
 
+var endpoints = []string{
+	webwxinit, webwxstatusnotify, webwxsync, webwxsendmsg, webwxgetcontact, webwxsendmsgimg,
+	webwxsendappmsg, webwxsendvideomsg, webwxbatchgetcontact, webwxoplog, webwxverifyuser,
+	synccheck, webwxuploadmedia, webwxgetmsgimg, webwxgetvoice, webwxgetvideo, webwxlogout,
+	webwxgetmedia, webwxupdatechatroom, webwxrevokemsg, webwxcheckupload, webwxpushloginurl,
+	webwxgeticon, webwxcreatechatroom, "/cgi-bin/mmwebwx-bin/webwxnewloginpage", "/jslogin",
+	"/cgi-bin/mmwebwx-bin/login", "/qrcode/",
+}
+
+// Endpoint 返回请求对应的接口名, 如 synccheck, webwxsync, 未知的接口返回 other
+// 用于按接口统计, 不会带上uuid等参数
+func Endpoint(u *url.URL) string {
+	for _, endpoint := range endpoints {
+		if u.Path == endpoint || strings.HasSuffix(endpoint, "/") && strings.HasPrefix(u.Path, endpoint) {
+			return path.Base(endpoint)
+		}
+	}
+	return "other"
+}
+
 type WechatDomain string
 
 func (w WechatDomain) BaseHost() string {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 )
 
 // User 抽象的用户结构: 好友 群组 公众号
@@ -32,6 +33,7 @@ type User struct {
 	DisplayName       string
 	KeyWord           string
 	EncryChatRoomId   string
+	ChatRoomOwner     string
 	UserName          string
 	NickName          string
 	HeadImgUrl        string
@@ -104,6 +106,9 @@ func (u *User) Detail(self *Self) error {
 	user := newMembers.First()
 	*u = *user
 	u.MemberList.init()
+	if self.details != nil {
+		self.details.put(u)
+	}
 	return nil
 }
 
@@ -134,9 +139,12 @@ func (u *User) IsMP() bool {
 //	return u.Self.Bot.Caller.WebWxRelationPin(req, u, 0)
 //}
 
+// 置顶联系人的ContactFlag标志位
+const contactFlagTop = 2048
+
 // IsPin 判断当前联系人(好友、群组、公众号)是否为置顶状态
 func (u *User) IsPin() bool {
-	return u.ContactFlag == 2051
+	return u.ContactFlag&contactFlagTop != 0
 }
 
 // 格式化emoji表情
@@ -147,54 +155,202 @@ func (u *User) formatEmoji() {
 }
 
 // Self 自己,当前登录用户对象
+// 联系人状态会被消息同步的协程更新, 读取返回的都是快照, 不要修改返回的切片
 type Self struct {
 	*User
 	Bot        *Bot
+	mu         sync.RWMutex
 	fileHelper *Friend
 	members    Members
 	friends    Friends
 	groups     Groups
-	ContactMap map[string]*User
+	contactMap map[string]*User
 	mps        Mps
+	details    *detailCache
 }
 
+// FindContactByUserName 根据UserName查找初始化和消息同步得到的联系人
 func (s *Self) FindContactByUserName(username string) (*User, bool) {
-	u, ok := s.ContactMap[username]
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.contactMap[username]
 	if !ok {
 		return nil, false
 	}
 	return u, true
 }
 
+// UserDetail 获取联系人详情, 优先使用缓存
+// 缓存有过期时间和容量限制, 联系人变更时失效, 同一个UserName的并发查询只会发起一个请求
+func (s *Self) UserDetail(username string) (*User, error) {
+	if username == s.UserName {
+		return s.User, nil
+	}
+	if s.details == nil {
+		return s.fetchDetail(username)
+	}
+	return s.details.get(username, s.fetchDetail)
+}
+
+// CachedUserDetail 只从缓存中获取联系人详情, 缓存中没有时不会发起请求
+func (s *Self) CachedUserDetail(username string) (*User, bool) {
+	if username == s.UserName {
+		return s.User, true
+	}
+	if s.details == nil {
+		return nil, false
+	}
+	return s.details.peek(username)
+}
+
+func (s *Self) fetchDetail(username string) (*User, error) {
+	request := s.Bot.Storage.Request
+	members, err := s.Bot.Caller.WebWxBatchGetContact(Members{{UserName: username}}, request)
+	if err != nil {
+		return nil, err
+	}
+	if members.Count() == 0 {
+		return nil, ErrNoSuchUserFoundError
+	}
+	members.init()
+	user := members.First()
+	user.MemberList.init()
+	return user, nil
+}
+
+// DetailCacheStats 获取联系人详情缓存的统计
+func (s *Self) DetailCacheStats() DetailCacheStats {
+	if s.details == nil {
+		return DetailCacheStats{}
+	}
+	return s.details.statistics()
+}
+
+// Contacts 获取初始化和消息同步得到的所有联系人的快照
+func (s *Self) Contacts() []*User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	contacts := make([]*User, 0, len(s.contactMap))
+	for _, user := range s.contactMap {
+		contacts = append(contacts, user)
+	}
+	return contacts
+}
+
+// ContactMap 获取以UserName为键的所有联系人的快照, 修改返回的map不会影响Self
+func (s *Self) ContactMap() map[string]*User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	contactMap := make(map[string]*User, len(s.contactMap))
+	for username, user := range s.contactMap {
+		contactMap[username] = user
+	}
+	return contactMap
+}
+
 // Members 获取所有的好友、群组、公众号信息
 func (s *Self) Members(update bool) (Members, error) {
 	// 首先判断缓存里有没有 如果没有则去更新缓存
-	if s.members == nil || update {
-		if err := s.updateMembers(); err != nil {
-			return nil, err
-		}
+	s.mu.RLock()
+	members := s.members
+	s.mu.RUnlock()
+	if members == nil || update {
+		return s.updateMembers()
 	}
-	return s.members, nil
+	return members, nil
 }
 
 // 更新联系人处理
-func (s *Self) updateMembers() error {
+func (s *Self) updateMembers() (Members, error) {
 	info := s.Bot.Storage.LoginInfo
 	members, err := s.Bot.Caller.WebWxGetContact(info)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	members.init()
+	s.setMembers(members)
+	return members, nil
+}
+
+// 替换联系人缓存, 好友、群组、公众号跟着一起替换
+func (s *Self) setMembers(members Members) {
+	friends, groups, mps := members.Friends(), members.Groups(), members.MPs()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.members = members
-	return nil
+	s.friends = friends
+	s.groups = groups
+	s.mps = mps
+}
+
+// UpdateContact 把本地修改后的联系人(如修改了备注或置顶)应用到联系人缓存, user会被复制
+func (s *Self) UpdateContact(user *User) {
+	s.applyContacts([]*User{user}, nil)
+}
+
+// 把webwxsync返回的联系人变更在一次加锁中应用到 contactMap 和联系人缓存
+// 缓存的切片不会被原地修改, 之前返回的快照不受影响
+func (s *Self) applyContacts(modContacts, delContacts []*User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.contactMap == nil {
+		s.contactMap = make(map[string]*User)
+	}
+	modified := make(map[string]*User, len(modContacts))
+	for _, contact := range modContacts {
+		c := *contact
+		s.contactMap[c.UserName] = &c
+		modified[c.UserName] = &c
+	}
+	deleted := make(map[string]bool, len(delContacts))
+	for _, contact := range delContacts {
+		delete(s.contactMap, contact.UserName)
+		deleted[contact.UserName] = true
+	}
+	if s.details != nil {
+		for _, contact := range modContacts {
+			s.details.invalidate(contact.UserName)
+		}
+		for _, contact := range delContacts {
+			s.details.invalidate(contact.UserName)
+		}
+	}
+	if s.members == nil {
+		return
+	}
+	members := make(Members, 0, len(s.members)+len(modContacts))
+	for _, member := range s.members {
+		if deleted[member.UserName] {
+			continue
+		}
+		if contact, ok := modified[member.UserName]; ok {
+			member = contact
+			delete(modified, member.UserName)
+		}
+		members = append(members, member)
+	}
+	// 新增的联系人按返回的顺序追加
+	for _, contact := range modContacts {
+		if c, ok := modified[contact.UserName]; ok && !deleted[c.UserName] {
+			members = append(members, c)
+			delete(modified, c.UserName)
+		}
+	}
+	s.members = members
+	s.friends = members.Friends()
+	s.groups = members.Groups()
+	s.mps = members.MPs()
 }
 
 // FileHelper 获取文件传输助手对象，封装成Friend返回
 //      fh, err := self.FileHelper() // or fh := openwechat.NewFriendHelper(self)
 func (s *Self) FileHelper() (*Friend, error) {
 	// 如果缓存里有，直接返回，否则去联系人里面找
-	if s.fileHelper != nil {
-		return s.fileHelper, nil
+	s.mu.RLock()
+	fileHelper := s.fileHelper
+	s.mu.RUnlock()
+	if fileHelper != nil {
+		return fileHelper, nil
 	}
 	members, err := s.Members(false)
 	if err != nil {
@@ -202,54 +358,122 @@ func (s *Self) FileHelper() (*Friend, error) {
 	}
 	users := members.SearchByUserName(1, "filehelper")
 	if users == nil {
-		s.fileHelper = NewFriendHelper()
+		fileHelper = NewFriendHelper()
 	} else {
-		s.fileHelper = &Friend{users.First()}
+		fileHelper = &Friend{users.First()}
 	}
-	return s.fileHelper, nil
+	s.mu.Lock()
+	s.fileHelper = fileHelper
+	s.mu.Unlock()
+	return fileHelper, nil
 }
 
 // Friends 获取所有的好友
 func (s *Self) Friends(update ...bool) (Friends, error) {
-	if s.friends == nil || (len(update) > 0 && update[0]) {
-		if _, err := s.Members(true); err != nil {
+	s.mu.RLock()
+	friends := s.friends
+	s.mu.RUnlock()
+	if friends == nil || (len(update) > 0 && update[0]) {
+		members, err := s.Members(true)
+		if err != nil {
 			return nil, err
 		}
-		s.friends = s.members.Friends()
+		friends = members.Friends()
 	}
-	return s.friends, nil
+	return friends, nil
 }
 
 // Groups 获取所有的群组
 func (s *Self) Groups(update ...bool) (Groups, error) {
-	if s.groups == nil || (len(update) > 0 && update[0]) {
-		if _, err := s.Members(true); err != nil {
+	s.mu.RLock()
+	groups := s.groups
+	s.mu.RUnlock()
+	if groups == nil || (len(update) > 0 && update[0]) {
+		members, err := s.Members(true)
+		if err != nil {
 			return nil, err
 		}
-		s.groups = s.members.Groups()
+		groups = members.Groups()
 	}
-	return s.groups, nil
+	return groups, nil
 }
 
 // Mps 获取所有的公众号
 func (s *Self) Mps(update ...bool) (Mps, error) {
-	if s.mps == nil || (len(update) > 0 && update[0]) {
-		if _, err := s.Members(true); err != nil {
+	s.mu.RLock()
+	mps := s.mps
+	s.mu.RUnlock()
+	if mps == nil || (len(update) > 0 && update[0]) {
+		members, err := s.Members(true)
+		if err != nil {
 			return nil, err
 		}
-		s.mps = s.members.MPs()
+		mps = members.MPs()
 	}
-	return s.mps, nil
+	return mps, nil
 }
 
 // UpdateMembersDetail 更新所有的联系人信息
-func (s *Self) UpdateMembersDetail() error {
+// 部分批次失败时, 成功的部分依然会更新, 返回 *BatchDetailError
+func (s *Self) UpdateMembersDetail(option ...DetailOption) error {
 	// 先获取所有的联系人
 	members, err := s.Members(false)
 	if err != nil {
 		return err
 	}
-	return members.detail(s)
+	return s.updateDetails(members, option...)
+}
+
+// UpdateGroupsDetail 批量更新所有群组的信息和群成员列表
+func (s *Self) UpdateGroupsDetail(option ...DetailOption) error {
+	groups, err := s.Groups()
+	if err != nil {
+		return err
+	}
+	members := make(Members, len(groups))
+	for i, group := range groups {
+		members[i] = group.User
+	}
+	return s.updateDetails(members, option...)
+}
+
+func (s *Self) updateDetails(members Members, option ...DetailOption) error {
+	var opt DetailOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+	details, err := s.FetchDetails(members, opt)
+	s.mergeDetails(details)
+	return err
+}
+
+// 用获取到的详情替换联系人缓存中对应的联系人, 期间通过消息同步新增的联系人保留
+func (s *Self) mergeDetails(details Members) {
+	if len(details) == 0 {
+		return
+	}
+	byUserName := make(map[string]*User, len(details))
+	for _, detail := range details {
+		byUserName[detail.UserName] = detail
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	members := make(Members, len(s.members))
+	for i, member := range s.members {
+		if detail, ok := byUserName[member.UserName]; ok {
+			member = detail
+		}
+		members[i] = member
+	}
+	for username := range s.contactMap {
+		if detail, ok := byUserName[username]; ok {
+			s.contactMap[username] = detail
+		}
+	}
+	s.members = members
+	s.friends = members.Friends()
+	s.groups = members.Groups()
+	s.mps = members.MPs()
 }
 
 func (s *Self) sendTextToUser(user *User, text string) (*SentMessage, error) {
@@ -307,7 +531,34 @@ func (s *Self) SendFileToFriend(friend *Friend, file *os.File) (*SentMessage, er
 //      self.SetRemarkNameToFriend(friend, "remark") // or friend.SetRemarkName("remark")
 func (s *Self) SetRemarkNameToFriend(friend *Friend, remarkName string) error {
 	req := s.Bot.Storage.Request
-	return s.Bot.Caller.WebWxOplog(req, remarkName, friend.UserName)
+	if err := s.Bot.Caller.WebWxOplog(req, remarkName, friend.UserName); err != nil {
+		return err
+	}
+	// 备注的拼音由服务端生成, 修改备注后原有的拼音已失效
+	friend.RemarkName = remarkName
+	friend.RemarkPYInitial = ""
+	friend.RemarkPYQuanPin = ""
+	return nil
+}
+
+// PinUser 将联系人(好友、群组、公众号)置顶
+func (s *Self) PinUser(user *User) error {
+	req := s.Bot.Storage.Request
+	if err := s.Bot.Caller.WebWxRelationPin(req, user, 1); err != nil {
+		return err
+	}
+	user.ContactFlag |= contactFlagTop
+	return nil
+}
+
+// UnPinUser 将联系人(好友、群组、公众号)取消置顶
+func (s *Self) UnPinUser(user *User) error {
+	req := s.Bot.Storage.Request
+	if err := s.Bot.Caller.WebWxRelationPin(req, user, 0); err != nil {
+		return err
+	}
+	user.ContactFlag &^= contactFlagTop
+	return nil
 }
 
 // CreateGroup 创建群聊
@@ -405,6 +656,17 @@ func (s *Self) SendTextToGroup(group *Group, text string) (*SentMessage, error)
 	return s.sendTextToUser(group.User, text)
 }
 
+// SendTextToGroupWithMention 发送@群成员的文本消息给群组
+//      self.SendTextToGroupWithMention(group, "hello", member1, member2)
+func (s *Self) SendTextToGroupWithMention(group *Group, text string, members ...*User) (*SentMessage, error) {
+	return s.sendTextToUser(group.User, MentionText(text, members...))
+}
+
+// SendTextToGroupWithMentionAll 发送@所有人的文本消息给群组
+func (s *Self) SendTextToGroupWithMentionAll(group *Group, text string) (*SentMessage, error) {
+	return s.sendTextToUser(group.User, MentionAllText(text))
+}
+
 // SendImageToGroup 发送图片消息给群组
 func (s *Self) SendImageToGroup(group *Group, file *os.File) (*SentMessage, error) {
 	return s.sendImageToUser(group.User, file)
@@ -599,56 +861,6 @@ func (m Members) MPs() Mps {
 	return mps
 }
 
-// 获取当前Members的详情
-func (m Members) detail(self *Self) error {
-	// 获取他们的数量
-	members := m
-
-	count := members.Count()
-	// 一次更新50个,分情况讨论
-
-	// 获取总的需要更新的次数
-	var times int
-	if count < 50 {
-		times = 1
-	} else {
-		times = count / 50
-	}
-	var newMembers Members
-	request := self.Bot.Storage.Request
-	var pMembers Members
-	// 分情况依次更新
-	for i := 1; i <= times; i++ {
-		if times == 1 {
-			pMembers = members
-		} else {
-			pMembers = members[(i-1)*50 : i*50]
-		}
-		nMembers, err := self.Bot.Caller.WebWxBatchGetContact(pMembers, request)
-		if err != nil {
-			return err
-		}
-		newMembers = append(newMembers, nMembers...)
-	}
-	// 最后判断是否全部更新完毕
-	total := times * 50
-	if total < count {
-		// 将全部剩余的更新完毕
-		left := count - total
-		pMembers = members[total : total+left]
-		nMembers, err := self.Bot.Caller.WebWxBatchGetContact(pMembers, request)
-		if err != nil {
-			return err
-		}
-		newMembers = append(newMembers, nMembers...)
-	}
-	if len(newMembers) > 0 {
-		newMembers.init()
-		self.members = newMembers
-	}
-	return nil
-}
-
 func (m Members) init() {
 	for _, member := range m {
 		member.formatEmoji()
@@ -688,5 +900,6 @@ func (s *Self) sendMessageWrapper(message *SentMessage, err error) (*SentMessage
 		return nil, err
 	}
 	message.Self = s
+	s.Bot.Events.Publish(&MessageSentEvent{Message: message})
 	return message, nil
 }
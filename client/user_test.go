@@ -0,0 +1,122 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestSelf returns a Self with count friends named @f0, @f1... and no bot.
+func newTestSelf(count int) *Self {
+	s := &Self{
+		User:       &User{UserName: "@me"},
+		contactMap: make(map[string]*User),
+		details:    newDetailCache(time.Minute, 100),
+	}
+	members := make(Members, count)
+	for i := range members {
+		members[i] = &User{UserName: fmt.Sprintf("@f%d", i), NickName: fmt.Sprintf("friend %d", i)}
+		s.contactMap[members[i].UserName] = members[i]
+	}
+	s.setMembers(members)
+	return s
+}
+
+func TestApplyContactsAtomic(t *testing.T) {
+	const count, rounds = 20, 200
+	s := newTestSelf(count)
+	s.details.put(&User{UserName: "@f0", NickName: "cached"})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// every delta deletes one friend and adds another, a reader never sees half of it
+				members, _ := s.Members(false)
+				friends, _ := s.Friends()
+				if len(members) != count || len(friends) != count || len(s.Contacts()) != count {
+					t.Errorf("saw %d members, %d friends, %d contacts, want %d", len(members), len(friends), len(s.Contacts()), count)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < rounds; i++ {
+		added := &User{UserName: fmt.Sprintf("@f%d", i+count)}
+		s.applyContacts([]*User{added}, []*User{{UserName: fmt.Sprintf("@f%d", i)}})
+		// the contacts are copied, changing the delta afterwards does not leak in
+		added.NickName = "changed"
+	}
+	close(done)
+	wg.Wait()
+
+	members, _ := s.Members(false)
+	for i, member := range members {
+		if want := fmt.Sprintf("@f%d", i+rounds); member.UserName != want || member.NickName != "" {
+			t.Fatalf("members[%d] = %s %q, want %s appended in order", i, member.UserName, member.NickName, want)
+		}
+	}
+	if _, ok := s.FindContactByUserName("@f0"); ok {
+		t.Error("deleted contact still found")
+	}
+	if _, ok := s.CachedUserDetail("@f0"); ok {
+		t.Error("detail of a deleted contact still cached")
+	}
+}
+
+func TestMergeDetailsKeepsSyncedContacts(t *testing.T) {
+	s := newTestSelf(3)
+	// a contact added by webwxsync while the details were fetched
+	s.applyContacts([]*User{{UserName: "@new"}}, nil)
+	s.mergeDetails(Members{{UserName: "@f1", NickName: "friend 1", Signature: "hello"}})
+
+	members, _ := s.Members(false)
+	if len(members) != 4 || members[3].UserName != "@new" {
+		t.Fatalf("members = %v, want the synced contact kept", members)
+	}
+	if members[1].Signature != "hello" {
+		t.Error("detail not merged into members")
+	}
+	if user, _ := s.FindContactByUserName("@f1"); user.Signature != "hello" {
+		t.Error("detail not merged into contacts")
+	}
+	if friends, _ := s.Friends(); len(friends) != 4 || friends[1].Signature != "hello" {
+		t.Error("friends not rebuilt from merged members")
+	}
+}
+
+func TestUpdateContact(t *testing.T) {
+	s := newTestSelf(2)
+	s.UpdateContact(&User{UserName: "@f1", NickName: "friend 1", RemarkName: "best"})
+	if friends, _ := s.Friends(); friends[1].RemarkName != "best" {
+		t.Error("friends still show the old remark")
+	}
+	if user, _ := s.FindContactByUserName("@f1"); user.RemarkName != "best" {
+		t.Error("contact still has the old remark")
+	}
+}
+
+func TestContactMapSnapshot(t *testing.T) {
+	s := newTestSelf(2)
+	contactMap := s.ContactMap()
+	if len(contactMap) != 2 || contactMap["@f1"].NickName != "friend 1" {
+		t.Fatalf("ContactMap() = %v", contactMap)
+	}
+	delete(contactMap, "@f1")
+	s.UpdateContact(&User{UserName: "@f2"})
+	if _, ok := s.FindContactByUserName("@f1"); !ok {
+		t.Error("deleting from the snapshot changed the contacts")
+	}
+	if _, ok := contactMap["@f2"]; ok {
+		t.Error("snapshot changed by a later contact")
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"hash/fnv"
+	"log"
+	"runtime"
+	"sync"
+)
+
+const defaultWorkerQueueSize = 64
+
+// 异步处理消息的协程池
+// 同一个会话的消息总是交给同一个协程, 保证按接收的顺序处理
+type workerPool struct {
+	mu     sync.RWMutex
+	closed bool
+	queues []chan *Message
+	wg     sync.WaitGroup
+}
+
+func newWorkerPool(workers, queueSize int, handle func(msg *Message)) *workerPool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if queueSize <= 0 {
+		queueSize = defaultWorkerQueueSize
+	}
+	p := &workerPool{queues: make([]chan *Message, workers)}
+	for i := range p.queues {
+		queue := make(chan *Message, queueSize)
+		p.queues[i] = queue
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for msg := range queue {
+				p.run(handle, msg)
+			}
+		}()
+	}
+	return p
+}
+
+func (p *workerPool) run(handle func(msg *Message), msg *Message) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("dispatch message %s panic: %v", msg.MsgId, r)
+		}
+	}()
+	handle(msg)
+}
+
+// 消息所属的会话, 自己发送的消息以接收者为会话
+func conversationKey(msg *Message) string {
+	if msg.Bot != nil && msg.Bot.self != nil && msg.IsSendBySelf() {
+		return msg.ToUserName
+	}
+	return msg.FromUserName
+}
+
+// 提交消息, 队列满了会阻塞, 已经关闭返回false
+func (p *workerPool) submit(msg *Message) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(conversationKey(msg)))
+	p.queues[hash.Sum32()%uint32(len(p.queues))] <- msg
+	return true
+}
+
+// 关闭协程池, 等待队列中的消息处理完成
+func (p *workerPool) close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, queue := range p.queues {
+			close(queue)
+		}
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
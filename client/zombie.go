@@ -0,0 +1,143 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ZombieStatus 好友关系的检测结果
+type ZombieStatus int
+
+const (
+	ZombieUnknown ZombieStatus = iota // 未检测或者返回了未知的状态
+	ZombieNormal                      // 正常好友
+	ZombieDeleted                     // 对方已删除自己
+	ZombieBlocked                     // 对方已拉黑自己
+)
+
+func (z ZombieStatus) String() string {
+	switch z {
+	case ZombieNormal:
+		return "Normal"
+	case ZombieDeleted:
+		return "Deleted"
+	case ZombieBlocked:
+		return "Blocked"
+	}
+	return "Unknown"
+}
+
+// 建群和拉人接口返回的群成员状态
+const (
+	memberStatusNormal  = 0
+	memberStatusBlocked = 3
+	memberStatusDeleted = 4
+)
+
+func zombieStatusOf(memberStatus int) ZombieStatus {
+	switch memberStatus {
+	case memberStatusNormal:
+		return ZombieNormal
+	case memberStatusBlocked:
+		return ZombieBlocked
+	case memberStatusDeleted:
+		return ZombieDeleted
+	}
+	return ZombieUnknown
+}
+
+// 建群和拉人接口的返回, MemberList中带有每个成员的状态
+type chatRoomMemberResponse struct {
+	BaseResponse BaseResponse
+	ChatRoomName string
+	MemberList   []struct {
+		UserName     string
+		MemberStatus int
+	}
+}
+
+func parseChatRoomMemberResponse(resp *http.Response, err error) (*chatRoomMemberResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var item chatRoomMemberResponse
+	if err = json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		return nil, err
+	}
+	// 有成员状态时即使Ret不为0也可以得出结果, 操作太频繁则需要停止检测
+	if item.BaseResponse.Ret == optTooOften || (!item.BaseResponse.Ok() && len(item.MemberList) == 0) {
+		return nil, item.BaseResponse
+	}
+	return &item, nil
+}
+
+// ZombieChecker 通过临时群检测僵尸好友
+// 第一次检测时用这批好友创建临时群, 之后的每批好友都拉进该群, 根据返回的成员状态判断是否被删除或拉黑
+// 每批检测完成后会把好友移出临时群, 检测结束后需要手动删除该群
+//      checker := self.NewZombieChecker("检测")
+//      result, err := checker.Check(friends...)
+type ZombieChecker struct {
+	self  *Self
+	topic string
+	group *Group
+}
+
+// NewZombieChecker 创建僵尸好友检测, topic为临时群的群名
+func (s *Self) NewZombieChecker(topic string) *ZombieChecker {
+	return &ZombieChecker{self: s, topic: topic}
+}
+
+// NewZombieCheckerInGroup 使用之前检测创建的临时群继续检测, 不会再创建新的群
+func (s *Self) NewZombieCheckerInGroup(group *Group) *ZombieChecker {
+	return &ZombieChecker{self: s, topic: group.NickName, group: group}
+}
+
+// Group 返回检测使用的临时群, 还没有创建时返回nil
+func (z *ZombieChecker) Group() *Group {
+	return z.group
+}
+
+// Check 检测一批好友, 返回UserName到检测结果的映射
+// 创建临时群至少需要2个好友, 所以第一批至少要有2个好友
+func (z *ZombieChecker) Check(friends ...*Friend) (map[string]ZombieStatus, error) {
+	if len(friends) == 0 {
+		return nil, nil
+	}
+	req := z.self.Bot.Storage.Request
+	info := z.self.Bot.Storage.LoginInfo
+	client := z.self.Bot.Caller.Client
+	var item *chatRoomMemberResponse
+	var err error
+	if z.group == nil {
+		if len(friends) < 2 {
+			return nil, errors.New("a group must be at least 2 members")
+		}
+		item, err = parseChatRoomMemberResponse(client.WebWxCreateChatRoom(req, info, z.topic, friends))
+		if err != nil {
+			return nil, err
+		}
+		z.group = &Group{User: &User{UserName: item.ChatRoomName, NickName: z.topic}}
+	} else {
+		item, err = parseChatRoomMemberResponse(client.AddMemberIntoChatRoom(req, info, z.group, friends...))
+		if err != nil {
+			return nil, err
+		}
+	}
+	result := make(map[string]ZombieStatus, len(friends))
+	for _, friend := range friends {
+		result[friend.UserName] = ZombieUnknown
+	}
+	users := make([]*User, 0, len(friends))
+	for _, member := range item.MemberList {
+		if _, ok := result[member.UserName]; !ok {
+			continue
+		}
+		result[member.UserName] = zombieStatusOf(member.MemberStatus)
+		users = append(users, &User{UserName: member.UserName})
+	}
+	// 移出失败不影响检测结果, 只是临时群里会留下这些成员
+	_ = z.self.Bot.Caller.RemoveFriendFromChatRoom(req, info, z.group, users...)
+	return result, nil
+}
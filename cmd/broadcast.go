@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"os"
+	"os/signal"
+	"strings"
+	"wx-cli/client"
+	"wx-cli/helper"
+)
+
+func runBroadcast(job *helper.BroadcastJob, retryFailed bool) error {
+	// Ctrl-C stops the broadcast instead of the whole program,
+	// the progress is saved and can be resumed later.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("Broadcast %s started, Ctrl-C to stop\n", job.Id)
+	err := h.RunBroadcast(ctx, job, retryFailed, func(target *helper.BroadcastTarget) {
+		fmt.Println(helper.BroadcastTargetToString(target))
+	})
+	fmt.Println(h.BroadcastReport(job))
+	if errors.Is(err, context.Canceled) {
+		fmt.Printf("Stopped, use `broadcast resume %s` to continue\n", job.Id)
+		return nil
+	}
+	return err
+}
+
+func (c cmdFactory) CmdBroadcast() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"bc",
+		},
+		Usage:       "Broadcast <send|resume|report>",
+		Description: "Send The Same Message To Many Friends And Groups",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "send",
+				Usage:     "Send text and/or file to targets, text is a template like `{{.Name}}`",
+				ArgsUsage: "<name|tag:<tag>|file:<path>>...",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "text", Aliases: []string{"t"}, Usage: "text template"},
+					&cli.StringFlag{Name: "file", Aliases: []string{"f"}, Usage: "image, video or file to send"},
+					yesFlag,
+				},
+				Action: func(ctx *cli.Context) error {
+					if !ctx.Args().Present() {
+						return errors.New("targets required")
+					}
+					payload := client.BroadcastPayload{Text: ctx.String("text"), FilePath: ctx.String("file")}
+					if payload.Text == "" && payload.FilePath == "" {
+						return errors.New("--text or --file required")
+					}
+					// without confirmation nobody checks a fuzzy match, names must be exact
+					resolve := h.ResolveTargets
+					if ctx.Bool("yes") {
+						resolve = h.ResolveExactTargets
+					}
+					users, err := resolve(ctx.Args().Slice())
+					if err != nil {
+						return err
+					}
+					if !ctx.Bool("yes") && !confirm("Broadcast to %d targets: %s?", users.Count(), membersName(users)) {
+						return errCanceled
+					}
+					job, err := h.NewBroadcastJob(users, payload)
+					if err != nil {
+						return err
+					}
+					return runBroadcast(job, false)
+				},
+			},
+			{
+				Name:      "resume",
+				Usage:     "Resume an interrupted broadcast",
+				ArgsUsage: "<id>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "retry-failed", Usage: "send to failed targets again"},
+				},
+				Action: func(ctx *cli.Context) error {
+					job, err := helper.LoadBroadcastJob(ctx.Args().First())
+					if err != nil {
+						return err
+					}
+					return runBroadcast(job, ctx.Bool("retry-failed"))
+				},
+			},
+			{
+				Name:      "report",
+				Usage:     "Show the result of each target",
+				ArgsUsage: "<id>",
+				Action: func(ctx *cli.Context) error {
+					job, err := helper.LoadBroadcastJob(ctx.Args().First())
+					if err != nil {
+						return err
+					}
+					fmt.Println(h.BroadcastReport(job))
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func (c cmdFactory) CmdTag() *cli.Command {
+	return &cli.Command{
+		Usage:       "Tag <add|rm|list>",
+		Description: "Tag Friends And Groups Locally",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "add",
+				Usage:     "Tag contacts",
+				ArgsUsage: "<tag> <name>...",
+				Action: func(ctx *cli.Context) error {
+					if ctx.NArg() < 2 {
+						return errors.New("tag and names required")
+					}
+					users, err := h.ResolveTargets(ctx.Args().Tail())
+					if err != nil {
+						return err
+					}
+					h.AddTag(ctx.Args().First(), users...)
+					return nil
+				},
+			},
+			{
+				Name:      "rm",
+				Usage:     "Untag contacts, or remove the tag if no name given",
+				ArgsUsage: "<tag> [name]...",
+				Action: func(ctx *cli.Context) error {
+					if !ctx.Args().Present() {
+						return errors.New("tag required")
+					}
+					h.RemoveTag(ctx.Args().First(), ctx.Args().Tail()...)
+					return nil
+				},
+			},
+			{
+				Name:      "list",
+				Usage:     "List tags, or contacts of a tag",
+				ArgsUsage: "[tag]",
+				Action: func(ctx *cli.Context) error {
+					if ctx.Args().Present() {
+						fmt.Println(strings.Join(h.Tagged(ctx.Args().First()), ", "))
+						return nil
+					}
+					for _, tag := range h.TagNames() {
+						fmt.Printf("%s (%d)\n", tag, len(h.Tagged(tag)))
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"github.com/urfave/cli/v2"
 	"reflect"
+	"strconv"
 	"strings"
+	"wx-cli/client"
 	"wx-cli/helper"
 )
 
@@ -60,11 +62,20 @@ func (c cmdFactory) CmdFetchMembers() *cli.Command {
 		Aliases: []string{
 			"fetch",
 		},
-		Usage:       "FetchMembers",
+		Usage:       "FetchMembers [--groups]",
 		Description: "Fetch All Members",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "groups",
+				Usage: "only fetch groups and their member lists",
+			},
+		},
 		Action: func(ctx *cli.Context) error {
 			fmt.Println("Fetching Members...")
-			err := h.FetchMembers()
+			err := h.FetchMembers(ctx.Bool("groups"), func(p client.DetailProgress) {
+				fmt.Printf("\rFetched %d/%d, failed %d", p.Done, p.Total, p.Failed)
+			})
+			fmt.Println()
 			if err != nil {
 				fmt.Println(err)
 			} else {
@@ -84,9 +95,9 @@ func (c cmdFactory) CmdAllMessages() *cli.Command {
 		Description: "Show All Messages",
 		Action: func(ctx *cli.Context) error {
 			messages := h.AllMessages()
-			for _, msg := range messages {
+			for i, msg := range messages {
 				text := h.MessageToString(msg)
-				fmt.Println(text)
+				fmt.Printf("#%d %s\n", i+1, text)
 			}
 			return nil
 		},
@@ -101,10 +112,91 @@ func (c cmdFactory) CmdMessages() *cli.Command {
 		Usage:       "Messages",
 		Description: "Show Unread Messages",
 		Action: func(ctx *cli.Context) error {
-			messages := h.UnreadMessages()
-			for _, msg := range messages {
+			messages, from := h.UnreadMessages()
+			for i, msg := range messages {
 				text := h.MessageToString(msg)
-				fmt.Println(text)
+				fmt.Printf("#%d %s\n", from+i, text)
+			}
+			return nil
+		},
+	}
+}
+
+func (c cmdFactory) CmdRecalls() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"rc",
+		},
+		Usage:       "Recalls [count]",
+		Description: "Show Recently Recalled Messages",
+		Action: func(ctx *cli.Context) error {
+			count := 10
+			if ctx.Args().Present() {
+				n, err := strconv.Atoi(ctx.Args().First())
+				if err != nil {
+					return err
+				}
+				count = n
+			}
+			recalls := h.RecentRecalls(count)
+			for _, recall := range recalls {
+				fmt.Println(h.RecallToString(recall))
+			}
+			return nil
+		},
+	}
+}
+
+func (c cmdFactory) CmdTo() *cli.Command {
+	return &cli.Command{
+		Usage:       "To <name>",
+		Description: "Set Message Target",
+		Action: func(ctx *cli.Context) error {
+			name := strings.Join(ctx.Args().Slice(), " ")
+			if err := h.SetTo(name); err != nil {
+				return err
+			}
+			fmt.Println("To", h.GetName(h.To()))
+			return nil
+		},
+	}
+}
+
+func (c cmdFactory) CmdSend() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"s",
+		},
+		Usage:       "Send [--at name] <text>",
+		Description: "Send Text To Target",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "at",
+				Usage: "mention group member by name, `all` alone for everyone",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			text := strings.Join(ctx.Args().Slice(), " ")
+			return h.SendText(text, ctx.StringSlice("at")...)
+		},
+	}
+}
+
+func (c cmdFactory) CmdContacts() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"c",
+		},
+		Usage:       "Contacts <keyword>",
+		Description: "Search Friends And Groups, Pinyin Supported",
+		Action: func(ctx *cli.Context) error {
+			keyword := strings.Join(ctx.Args().Slice(), " ")
+			users, err := h.SearchContacts(keyword, 10)
+			if err != nil {
+				return err
+			}
+			for _, user := range users {
+				fmt.Println(h.GetName(user))
 			}
 			return nil
 		},
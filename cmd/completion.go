@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"strings"
+	"unicode"
+	"wx-cli/util"
+)
+
+// Complete implements liner.WordCompleter,
+// the first word completes to commands and the others to contacts.
+func Complete(line string, pos int) (head string, completions []string, tail string) {
+	head, word, tail := currentWord(line, pos)
+	if strings.TrimSpace(head) == "" {
+		return head, completeCommand(word), tail
+	}
+	keyword := strings.Trim(word, `"'`)
+	if keyword == "" {
+		return head, nil, tail
+	}
+	for _, name := range h.CompleteContact(keyword) {
+		completions = append(completions, util.QuoteArg(name))
+	}
+	return head, completions, tail
+}
+
+func completeCommand(prefix string) []string {
+	var names []string
+	for _, c := range CliCommands {
+		for _, name := range c.Names() {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// currentWord splits line at pos into the text before the word under cursor,
+// the word itself and the text after the cursor.
+func currentWord(line string, pos int) (string, string, string) {
+	runes := []rune(line)
+	if pos > len(runes) {
+		pos = len(runes)
+	}
+	start := 0
+	var quote rune
+	var escaped bool
+	for i, r := range runes[:pos] {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case unicode.IsSpace(r):
+			start = i + 1
+		}
+	}
+	return string(runes[:start]), string(runes[start:pos]), string(runes[pos:])
+}
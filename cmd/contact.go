@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"strings"
+)
+
+func (c cmdFactory) CmdRemark() *cli.Command {
+	return &cli.Command{
+		Usage:       "Remark <friend> [remark name]",
+		Description: "Set Remark Name Of Friend, Clear It If Remark Name Is Omitted",
+		Action: func(ctx *cli.Context) error {
+			if !ctx.Args().Present() {
+				return errors.New("friend required")
+			}
+			friend, err := h.FindFriend(ctx.Args().First())
+			if err != nil {
+				return err
+			}
+			remarkName := strings.Join(ctx.Args().Tail(), " ")
+			if err = h.SetRemarkName(friend, remarkName); err != nil {
+				return err
+			}
+			if remarkName == "" {
+				fmt.Println("Remark cleared")
+			} else {
+				fmt.Println("Remark set to", remarkName)
+			}
+			return nil
+		},
+	}
+}
+
+func (c cmdFactory) CmdPin() *cli.Command {
+	return &cli.Command{
+		Usage:       "Pin <name>",
+		Description: "Pin Chat To Top",
+		Action: func(ctx *cli.Context) error {
+			user, err := h.FindContact(strings.Join(ctx.Args().Slice(), " "))
+			if err != nil {
+				return err
+			}
+			if err = h.Pin(user); err != nil {
+				return err
+			}
+			fmt.Println("Pinned", h.GetName(user))
+			return nil
+		},
+	}
+}
+
+func (c cmdFactory) CmdUnPin() *cli.Command {
+	return &cli.Command{
+		Usage:       "UnPin <name>",
+		Description: "Unpin Chat",
+		Action: func(ctx *cli.Context) error {
+			user, err := h.FindContact(strings.Join(ctx.Args().Slice(), " "))
+			if err != nil {
+				return err
+			}
+			if err = h.UnPin(user); err != nil {
+				return err
+			}
+			fmt.Println("Unpinned", h.GetName(user))
+			return nil
+		},
+	}
+}
+
+func (c cmdFactory) CmdProfile() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"p",
+		},
+		Usage:       "Profile <name>",
+		Description: "Show Profile Of Friend Or Group",
+		Action: func(ctx *cli.Context) error {
+			user, err := h.FindContact(strings.Join(ctx.Args().Slice(), " "))
+			if err != nil {
+				return err
+			}
+			user, err = h.Profile(user)
+			if err != nil {
+				return err
+			}
+			fmt.Println(h.ProfileToString(user))
+			return nil
+		},
+	}
+}
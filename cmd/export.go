@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"strings"
+	"time"
+	"wx-cli/export"
+	"wx-cli/helper"
+)
+
+const dateFormat = "2006-01-02"
+
+// filterFlags select messages by date range and type, see parseFilter.
+var filterFlags = []cli.Flag{
+	&cli.StringFlag{Name: "from", Usage: "messages since `date` like 2006-01-02"},
+	&cli.StringFlag{Name: "to", Usage: "messages until `date`, inclusive"},
+	&cli.StringFlag{Name: "type", Aliases: []string{"t"}, Usage: "comma separated types: " + strings.Join(export.Kinds, ",")},
+}
+
+func parseFilter(ctx *cli.Context) (export.Filter, error) {
+	var filter export.Filter
+	var err error
+	if ctx.IsSet("from") {
+		if filter.From, err = time.ParseInLocation(dateFormat, ctx.String("from"), time.Local); err != nil {
+			return filter, err
+		}
+	}
+	if ctx.IsSet("to") {
+		to, err := time.ParseInLocation(dateFormat, ctx.String("to"), time.Local)
+		if err != nil {
+			return filter, err
+		}
+		filter.To = to.AddDate(0, 0, 1)
+	}
+	if ctx.IsSet("type") {
+		for _, kind := range strings.Split(ctx.String("type"), ",") {
+			kind = strings.TrimSpace(kind)
+			if !containsKind(kind) {
+				return filter, fmt.Errorf("unknown type %q", kind)
+			}
+			filter.Kinds = append(filter.Kinds, kind)
+		}
+	}
+	return filter, nil
+}
+
+func (c cmdFactory) CmdExport() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"ex",
+		},
+		Usage:       "Export [name]",
+		Description: "Export One Or All Conversations To Markdown, HTML Or JSONL",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{Name: "format", Aliases: []string{"f"}, Value: "md", Usage: "md, html or jsonl"},
+			&cli.StringFlag{Name: "dir", Aliases: []string{"o"}, Usage: "output directory, export_<time> by default"},
+			&cli.BoolFlag{Name: "no-media", Usage: "do not copy images, voices, videos and files"},
+		}, filterFlags...),
+		Action: func(ctx *cli.Context) error {
+			format, err := export.ParseFormat(ctx.String("format"))
+			if err != nil {
+				return err
+			}
+			opt := helper.ExportOptions{
+				Name:   strings.Join(ctx.Args().Slice(), " "),
+				Format: format,
+				Dir:    ctx.String("dir"),
+				Media:  !ctx.Bool("no-media"),
+			}
+			if opt.Dir == "" {
+				opt.Dir = "export_" + time.Now().Format("20060102150405")
+			}
+			if opt.Filter, err = parseFilter(ctx); err != nil {
+				return err
+			}
+			files, err := h.Export(opt)
+			for _, file := range files {
+				fmt.Println(file)
+			}
+			return err
+		},
+	}
+}
+
+func containsKind(kind string) bool {
+	for _, k := range export.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
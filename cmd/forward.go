@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"strconv"
+	"strings"
+	"wx-cli/client"
+)
+
+func (c cmdFactory) CmdForward() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"fw",
+		},
+		Usage:       "Forward <n> <target>...",
+		Description: "Forward The nth Message To Friends Or Groups",
+		Action: func(ctx *cli.Context) error {
+			if ctx.NArg() < 2 {
+				return errors.New("message number and targets required")
+			}
+			n, err := strconv.Atoi(strings.TrimPrefix(ctx.Args().First(), "#"))
+			if err != nil {
+				return err
+			}
+			msg, err := h.MessageAt(n)
+			if err != nil {
+				return err
+			}
+			targets := make([]*client.User, 0, ctx.NArg()-1)
+			for _, name := range ctx.Args().Tail() {
+				target, err := h.FindContact(name)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, target)
+			}
+			for i, err := range h.Forward(msg, targets) {
+				if err != nil {
+					fmt.Printf("[%s] failed: %v\n", h.GetName(targets[i]), err)
+				} else {
+					fmt.Printf("[%s] forwarded\n", h.GetName(targets[i]))
+				}
+			}
+			return nil
+		},
+	}
+}
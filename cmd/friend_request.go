@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"strconv"
+	"strings"
+)
+
+func friendRequestId(ctx *cli.Context) (int, error) {
+	if !ctx.Args().Present() {
+		return 0, errors.New("friend request id required")
+	}
+	return strconv.Atoi(strings.TrimPrefix(ctx.Args().First(), "#"))
+}
+
+func (c cmdFactory) CmdRequests() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"req",
+		},
+		Usage:       "Requests [--all]",
+		Description: "Show Pending Friend Requests",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "include accepted and ignored requests",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			for _, request := range h.FriendRequests(!ctx.Bool("all")) {
+				fmt.Println(h.FriendRequestToString(request))
+			}
+			return nil
+		},
+	}
+}
+
+func (c cmdFactory) CmdAccept() *cli.Command {
+	return &cli.Command{
+		Usage:       "Accept <id> [verify text]",
+		Description: "Accept Friend Request",
+		Action: func(ctx *cli.Context) error {
+			id, err := friendRequestId(ctx)
+			if err != nil {
+				return err
+			}
+			verifyContent := strings.Join(ctx.Args().Tail(), " ")
+			if err = h.AcceptFriendRequest(id, verifyContent); err != nil {
+				return err
+			}
+			fmt.Println("Accepted")
+			return nil
+		},
+	}
+}
+
+func (c cmdFactory) CmdIgnore() *cli.Command {
+	return &cli.Command{
+		Usage:       "Ignore <id>",
+		Description: "Ignore Friend Request",
+		Action: func(ctx *cli.Context) error {
+			id, err := friendRequestId(ctx)
+			if err != nil {
+				return err
+			}
+			if err = h.IgnoreFriendRequest(id); err != nil {
+				return err
+			}
+			fmt.Println("Ignored")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"strings"
+	"wx-cli/client"
+)
+
+var errCanceled = errors.New("canceled")
+
+var yesFlag = &cli.BoolFlag{
+	Name:    "yes",
+	Aliases: []string{"y"},
+	Usage:   "skip confirmation",
+}
+
+func friendsName(friends client.Friends) string {
+	names := make([]string, len(friends))
+	for i, friend := range friends {
+		names[i] = h.GetName(friend.User)
+	}
+	return strings.Join(names, ", ")
+}
+
+func membersName(members client.Members) string {
+	names := make([]string, len(members))
+	for i, member := range members {
+		names[i] = h.GetName(member)
+	}
+	return strings.Join(names, ", ")
+}
+
+func printGroupMembers(group *client.Group) error {
+	members, err := h.GroupMembers(group)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s (%d members)\n", h.GetName(group.User), members.Count())
+	for _, member := range members {
+		name := h.GetName(member)
+		if member.DisplayName != "" && member.DisplayName != member.NickName {
+			name = fmt.Sprintf("%s (%s)", member.DisplayName, member.NickName)
+		}
+		if h.IsGroupOwner(group, member) {
+			name += " [Owner]"
+		}
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func (c cmdFactory) CmdGroup() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"g",
+		},
+		Usage:       "Group <create|rename|invite|kick|members>",
+		Description: "Manage Groups",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Create a group with at least 2 friends",
+				ArgsUsage: "<topic> <friend> <friend>...",
+				Flags:     []cli.Flag{yesFlag},
+				Action: func(ctx *cli.Context) error {
+					if ctx.NArg() < 3 {
+						return errors.New("a group must be at least 2 members")
+					}
+					topic := ctx.Args().First()
+					friends, err := h.FindFriends(ctx.Args().Tail())
+					if err != nil {
+						return err
+					}
+					if !ctx.Bool("yes") && !confirm("Create group %s with %s?", topic, friendsName(friends)) {
+						return errCanceled
+					}
+					group, err := h.CreateGroup(topic, friends)
+					if err != nil {
+						return err
+					}
+					return printGroupMembers(group)
+				},
+			},
+			{
+				Name:      "rename",
+				Usage:     "Rename a group",
+				ArgsUsage: "<group> <new name>",
+				Flags:     []cli.Flag{yesFlag},
+				Action: func(ctx *cli.Context) error {
+					if ctx.NArg() != 2 {
+						return errors.New("group and new name required")
+					}
+					group, err := h.FindGroup(ctx.Args().Get(0))
+					if err != nil {
+						return err
+					}
+					newName := ctx.Args().Get(1)
+					if !ctx.Bool("yes") && !confirm("Rename group %s to %s?", h.GetName(group.User), newName) {
+						return errCanceled
+					}
+					if err = h.RenameGroup(group, newName); err != nil {
+						return err
+					}
+					fmt.Println("Renamed to", newName)
+					return nil
+				},
+			},
+			{
+				Name:      "invite",
+				Usage:     "Invite friends into a group",
+				ArgsUsage: "<group> <friend>...",
+				Flags:     []cli.Flag{yesFlag},
+				Action: func(ctx *cli.Context) error {
+					if ctx.NArg() < 2 {
+						return errors.New("group and friends required")
+					}
+					group, err := h.FindGroup(ctx.Args().First())
+					if err != nil {
+						return err
+					}
+					friends, err := h.FindFriends(ctx.Args().Tail())
+					if err != nil {
+						return err
+					}
+					if !ctx.Bool("yes") && !confirm("Invite %s into %s?", friendsName(friends), h.GetName(group.User)) {
+						return errCanceled
+					}
+					if err = h.InviteIntoGroup(group, friends); err != nil {
+						return err
+					}
+					return printGroupMembers(group)
+				},
+			},
+			{
+				Name:      "kick",
+				Usage:     "Remove members from a group, group owner required",
+				ArgsUsage: "<group> <member>...",
+				Flags:     []cli.Flag{yesFlag},
+				Action: func(ctx *cli.Context) error {
+					if ctx.NArg() < 2 {
+						return errors.New("group and members required")
+					}
+					group, err := h.FindGroup(ctx.Args().First())
+					if err != nil {
+						return err
+					}
+					members, err := h.FindGroupMembers(group, ctx.Args().Tail())
+					if err != nil {
+						return err
+					}
+					if !ctx.Bool("yes") && !confirm("Remove %s from %s?", membersName(members), h.GetName(group.User)) {
+						return errCanceled
+					}
+					if err = h.KickFromGroup(group, members); err != nil {
+						return err
+					}
+					return printGroupMembers(group)
+				},
+			},
+			{
+				Name:      "members",
+				Usage:     "List members of a group",
+				ArgsUsage: "<group>",
+				Action: func(ctx *cli.Context) error {
+					group, err := h.FindGroup(strings.Join(ctx.Args().Slice(), " "))
+					if err != nil {
+						return err
+					}
+					return printGroupMembers(group)
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"wx-cli/plugins"
+)
+
+func pluginStatusToString(status plugins.Status) string {
+	text := fmt.Sprintf("[%s] %s", status.State, status.Name)
+	if status.Pid > 0 {
+		text += fmt.Sprintf(" pid %d", status.Pid)
+	}
+	if status.Restarts > 0 {
+		text += fmt.Sprintf(" restarts %d", status.Restarts)
+	}
+	if status.Dropped > 0 {
+		text += fmt.Sprintf(" dropped %d", status.Dropped)
+	}
+	if status.LastError != "" {
+		text += ": " + status.LastError
+	}
+	return text
+}
+
+func pluginCommand(name, usage string, action func(name string) error) *cli.Command {
+	return &cli.Command{
+		Name:      name,
+		Usage:     usage,
+		ArgsUsage: "<name>",
+		Action: func(ctx *cli.Context) error {
+			return action(ctx.Args().First())
+		},
+	}
+}
+
+func (c cmdFactory) CmdPlugin() *cli.Command {
+	return &cli.Command{
+		Usage:       "Plugin <list|start|stop|restart>",
+		Description: "Manage Plugin Processes Configured In config.json",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "Show the state of plugins",
+				Action: func(ctx *cli.Context) error {
+					for _, status := range h.Plugins() {
+						fmt.Println(pluginStatusToString(status))
+					}
+					return nil
+				},
+			},
+			pluginCommand("start", "Start a plugin", h.StartPlugin),
+			pluginCommand("stop", "Stop a plugin", h.StopPlugin),
+			pluginCommand("restart", "Restart a plugin", h.RestartPlugin),
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"strconv"
+)
+
+func (c cmdFactory) CmdRevoke() *cli.Command {
+	return &cli.Command{
+		Usage:       "Revoke [count]",
+		Description: "Revoke The Last Sent Messages Within 2 Minutes",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "list", Aliases: []string{"l"}, Usage: "list recently sent messages and the revoke window left"},
+			yesFlag,
+		},
+		Action: func(ctx *cli.Context) error {
+			count := 1
+			if ctx.Args().Present() {
+				n, err := strconv.Atoi(ctx.Args().First())
+				if err != nil {
+					return err
+				}
+				count = n
+			}
+			if ctx.Bool("list") {
+				if !ctx.Args().Present() {
+					count = 10
+				}
+				for _, sent := range h.RecentSent(count) {
+					fmt.Println(h.SentToString(sent))
+				}
+				return nil
+			}
+			revocable := h.Revocable(count)
+			if len(revocable) == 0 {
+				return errors.New("no message can be revoked")
+			}
+			for _, sent := range revocable {
+				fmt.Println(h.SentToString(sent))
+			}
+			if !ctx.Bool("yes") && !confirm("Revoke %d messages?", len(revocable)) {
+				return errCanceled
+			}
+			for _, sent := range revocable {
+				if err := h.Revoke(sent); err != nil {
+					fmt.Printf("#%d: %v\n", sent.Id, err)
+				}
+			}
+			return nil
+		},
+	}
+}
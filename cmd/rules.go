@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"strings"
+	"time"
+	"wx-cli/rules"
+)
+
+func ruleToString(rule *rules.Rule) string {
+	state := "on"
+	if rule.Disabled {
+		state = "off"
+	}
+	var conditions []string
+	m := rule.Match
+	if len(m.Senders) > 0 {
+		conditions = append(conditions, "sender="+strings.Join(m.Senders, "|"))
+	}
+	if len(m.Groups) > 0 {
+		conditions = append(conditions, "group="+strings.Join(m.Groups, "|"))
+	}
+	if len(m.Keywords) > 0 {
+		conditions = append(conditions, "keyword="+strings.Join(m.Keywords, "|"))
+	}
+	if m.Regex != "" {
+		conditions = append(conditions, "regex="+m.Regex)
+	}
+	if len(m.Types) > 0 {
+		conditions = append(conditions, "type="+strings.Join(m.Types, "|"))
+	}
+	if m.At {
+		conditions = append(conditions, "@me")
+	}
+	if m.Time != "" {
+		conditions = append(conditions, "time="+m.Time)
+	}
+	actions := make([]string, len(rule.Actions))
+	for i, action := range rule.Actions {
+		actions[i] = action.Type
+	}
+	return fmt.Sprintf("[%s] %s: %s -> %s", state, rule.Name, strings.Join(conditions, " "), strings.Join(actions, ", "))
+}
+
+func (c cmdFactory) CmdRules() *cli.Command {
+	return &cli.Command{
+		Usage:       "Rules <list|enable|disable|reload|test>",
+		Description: "Manage Auto-Reply Rules Of The Rules File",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "List rules in order",
+				Action: func(ctx *cli.Context) error {
+					for _, rule := range h.Rules() {
+						fmt.Println(ruleToString(rule))
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "enable",
+				Usage:     "Enable a rule",
+				ArgsUsage: "<name>",
+				Action: func(ctx *cli.Context) error {
+					return h.EnableRule(ctx.Args().First())
+				},
+			},
+			{
+				Name:      "disable",
+				Usage:     "Disable a rule",
+				ArgsUsage: "<name>",
+				Action: func(ctx *cli.Context) error {
+					return h.DisableRule(ctx.Args().First())
+				},
+			},
+			{
+				Name:  "reload",
+				Usage: "Reload the rules file now",
+				Action: func(ctx *cli.Context) error {
+					return h.ReloadRules()
+				},
+			},
+			{
+				Name:      "test",
+				Usage:     "Show the rules a sample message matches without doing anything",
+				ArgsUsage: "<text>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "sender", Aliases: []string{"s"}, Usage: "sender `name`"},
+					&cli.StringFlag{Name: "group", Aliases: []string{"g"}, Usage: "group `name`, empty for a friend message"},
+					&cli.StringFlag{Name: "type", Aliases: []string{"t"}, Value: "text", Usage: "message type"},
+					&cli.BoolFlag{Name: "at", Usage: "the message @ me"},
+					&cli.StringFlag{Name: "time", Usage: "received at `15:04`, now by default"},
+				},
+				Action: func(ctx *cli.Context) error {
+					sample := &rules.Sample{
+						Sender: ctx.String("sender"),
+						Group:  ctx.String("group"),
+						Text:   strings.Join(ctx.Args().Slice(), " "),
+						Type:   ctx.String("type"),
+						At:     ctx.Bool("at"),
+						Time:   time.Now(),
+					}
+					if ctx.IsSet("time") {
+						clock, err := time.ParseInLocation("15:04", ctx.String("time"), time.Local)
+						if err != nil {
+							return errors.New("invalid time, use 15:04")
+						}
+						now := time.Now()
+						sample.Time = time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, time.Local)
+					}
+					results := h.TestRules(sample)
+					if len(results) == 0 {
+						fmt.Println("No rule matches")
+						return nil
+					}
+					for _, result := range results {
+						fmt.Println(ruleToString(result.Rule))
+						for _, action := range result.Actions {
+							fmt.Println("  " + action)
+						}
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"strconv"
+	"time"
+	"wx-cli/helper"
+	"wx-cli/scheduler"
+)
+
+var scheduleFlags = []cli.Flag{
+	&cli.StringFlag{Name: "at", Usage: "send at `2006-01-02 15:04` or `15:04`"},
+	&cli.DurationFlag{Name: "in", Usage: "send after a duration like `10m`"},
+	&cli.StringFlag{Name: "cron", Usage: "send repeatedly, like `0 9 * * 1-5`"},
+	&cli.StringFlag{Name: "text", Aliases: []string{"t"}, Usage: "text to send"},
+	&cli.StringFlag{Name: "file", Aliases: []string{"f"}, Usage: "image, video or file to send"},
+}
+
+// parseAt parses a local time, a bare clock time means its next occurrence.
+func parseAt(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local); err == nil {
+		return t, nil
+	}
+	clock, err := time.ParseInLocation("15:04", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	now := time.Now()
+	t := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, time.Local)
+	if !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// applyScheduleFlags sets the fields given on the command line.
+func applyScheduleFlags(ctx *cli.Context, job *scheduler.Job) error {
+	if ctx.IsSet("text") {
+		job.Text = ctx.String("text")
+	}
+	if ctx.IsSet("file") {
+		job.FilePath = ctx.String("file")
+	}
+	switch {
+	case ctx.IsSet("cron"):
+		job.Cron = ctx.String("cron")
+		job.At = 0
+	case ctx.IsSet("at"):
+		t, err := parseAt(ctx.String("at"))
+		if err != nil {
+			return err
+		}
+		job.At = t.Unix()
+		job.Cron = ""
+	case ctx.IsSet("in"):
+		job.At = time.Now().Add(ctx.Duration("in")).Unix()
+		job.Cron = ""
+	}
+	return nil
+}
+
+func scheduleId(ctx *cli.Context) (int, error) {
+	id, err := strconv.Atoi(ctx.Args().First())
+	if err != nil {
+		return 0, errors.New("job id required")
+	}
+	return id, nil
+}
+
+func (c cmdFactory) CmdSchedule() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"sch",
+		},
+		Usage:       "Schedule <add|list|edit|cancel|history>",
+		Description: "Send Messages Later Or Repeatedly",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "add",
+				Usage:     "Schedule text and/or file to a friend or group",
+				ArgsUsage: "<name>",
+				Flags:     scheduleFlags,
+				Action: func(ctx *cli.Context) error {
+					if !ctx.Args().Present() {
+						return errors.New("target required")
+					}
+					job := &scheduler.Job{Target: ctx.Args().First()}
+					if err := applyScheduleFlags(ctx, job); err != nil {
+						return err
+					}
+					job, err := h.AddSchedule(job)
+					if err != nil {
+						return err
+					}
+					fmt.Println(helper.ScheduleToString(job))
+					return nil
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "List scheduled jobs",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "all", Aliases: []string{"a"}, Usage: "include finished jobs"},
+				},
+				Action: func(ctx *cli.Context) error {
+					for _, job := range h.Schedules() {
+						if job.Done && !ctx.Bool("all") {
+							continue
+						}
+						fmt.Println(helper.ScheduleToString(job))
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "edit",
+				Usage:     "Change the time, text or file of a job",
+				ArgsUsage: "<id>",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{Name: "to", Usage: "change the target"},
+				}, scheduleFlags...),
+				Action: func(ctx *cli.Context) error {
+					id, err := scheduleId(ctx)
+					if err != nil {
+						return err
+					}
+					var target string
+					if ctx.IsSet("to") {
+						user, err := h.FindContact(ctx.String("to"))
+						if err != nil {
+							return err
+						}
+						target = h.GetName(user)
+					}
+					job, err := h.EditSchedule(id, func(job *scheduler.Job) error {
+						if target != "" {
+							job.Target = target
+						}
+						return applyScheduleFlags(ctx, job)
+					})
+					if err != nil {
+						return err
+					}
+					fmt.Println(helper.ScheduleToString(job))
+					return nil
+				},
+			},
+			{
+				Name:      "cancel",
+				Usage:     "Cancel a job",
+				ArgsUsage: "<id>",
+				Action: func(ctx *cli.Context) error {
+					id, err := scheduleId(ctx)
+					if err != nil {
+						return err
+					}
+					return h.CancelSchedule(id)
+				},
+			},
+			{
+				Name:      "history",
+				Usage:     "Show the delivery results of a job",
+				ArgsUsage: "<id>",
+				Action: func(ctx *cli.Context) error {
+					id, err := scheduleId(ctx)
+					if err != nil {
+						return err
+					}
+					job, err := h.Schedule(id)
+					if err != nil {
+						return err
+					}
+					fmt.Println(helper.ScheduleToString(job))
+					for _, run := range job.History {
+						fmt.Println(helper.RunToString(run))
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"strings"
+	"wx-cli/helper"
+)
+
+func (c cmdFactory) CmdSearch() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"find",
+		},
+		Usage:       "Search [keywords], or list the messages of the filters",
+		Description: "Search Stored Messages, Senders And Article Titles",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{Name: "in", Usage: "only the conversation with friend or group `name`"},
+			&cli.StringFlag{Name: "sender", Aliases: []string{"s"}, Usage: "only senders whose name contains `name`"},
+			&cli.IntFlag{Name: "limit", Aliases: []string{"n"}, Value: 20, Usage: "show at most n results, 0 for all"},
+		}, filterFlags...),
+		Action: func(ctx *cli.Context) error {
+			query := strings.Join(ctx.Args().Slice(), " ")
+			filter, err := parseFilter(ctx)
+			if err != nil {
+				return err
+			}
+			results, err := h.Search(helper.SearchOptions{
+				Query:        query,
+				Conversation: ctx.String("in"),
+				Sender:       ctx.String("sender"),
+				Filter:       filter,
+				Limit:        ctx.Int("limit"),
+			})
+			if err != nil {
+				return err
+			}
+			if len(results) == 0 {
+				fmt.Println("No messages found")
+				return nil
+			}
+			for _, result := range results {
+				fmt.Println(h.SearchResultToString(result, query))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"os"
+	"wx-cli/helper"
+)
+
+func (c cmdFactory) CmdStats() *cli.Command {
+	return &cli.Command{
+		Usage:       "Stats [name]",
+		Description: "Show Request, Sync, Message And Dispatch Metrics",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "raw", Usage: "print in the Prometheus text format"},
+		},
+		Action: func(ctx *cli.Context) error {
+			filter := ctx.Args().First()
+			if ctx.Bool("raw") {
+				return h.WriteStats(os.Stdout, filter)
+			}
+			families := h.Stats(filter)
+			if len(families) == 0 {
+				return fmt.Errorf("no metric matches %s", filter)
+			}
+			for _, f := range families {
+				fmt.Println(helper.FamilyToString(f))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"wx-cli/helper"
+)
+
+func (c cmdFactory) CmdStatus() *cli.Command {
+	return &cli.Command{
+		Usage:       "Status [--json]",
+		Description: "Show Login State And Session Diagnostics",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "json", Usage: "print as json"},
+		},
+		Action: func(ctx *cli.Context) error {
+			status := h.Status()
+			if !ctx.Bool("json") {
+				fmt.Println(helper.StatusToString(status))
+				return nil
+			}
+			b, err := json.MarshalIndent(status, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+			return nil
+		},
+	}
+}
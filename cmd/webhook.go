@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"strings"
+	"wx-cli/util"
+)
+
+func (c cmdFactory) CmdWebhook() *cli.Command {
+	return &cli.Command{
+		Aliases: []string{
+			"wh",
+		},
+		Usage:       "Webhook <status|dead|requeue>",
+		Description: "Show The Webhook Queue And Dead Letters",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "status",
+				Usage: "Show webhooks and the queue size",
+				Action: func(ctx *cli.Context) error {
+					status, err := h.WebhookStatus()
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Webhooks: %s\n", strings.Join(status.Webhooks, ", "))
+					fmt.Printf("Pending: %d, dead letters: %d\n", status.Pending, status.Dead)
+					return nil
+				},
+			},
+			{
+				Name:  "dead",
+				Usage: "List deliveries that failed every attempt",
+				Action: func(ctx *cli.Context) error {
+					deliveries, err := h.WebhookDeadLetters()
+					if err != nil {
+						return err
+					}
+					for _, d := range deliveries {
+						fmt.Printf("[%s] %s %d attempts: %s\n", util.Int64ToTimeString(d.CreatedAt), d.Id, d.Attempts, d.LastError)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "requeue",
+				Usage: "Move the dead letters back to the queue",
+				Action: func(ctx *cli.Context) error {
+					n, err := h.RequeueWebhookDeadLetters()
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Requeued %d deliveries\n", n)
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"os"
+	"os/signal"
+	"wx-cli/helper"
+)
+
+func runZombieAudit(audit *helper.ZombieAudit) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("Checking %d friends, Ctrl-C to stop\n", audit.Pending())
+	group, err := h.RunZombieAudit(ctx, audit, func(target *helper.ZombieTarget) {
+		fmt.Println(helper.ZombieTargetToString(target))
+	})
+	if group != nil {
+		fmt.Printf("Temporary group %s was created for the check, delete it on your phone\n", h.GetName(group.User))
+	}
+	fmt.Println(helper.ZombieReport(audit, false))
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Stopped, use `zombie resume` to continue")
+		return nil
+	}
+	return err
+}
+
+func (c cmdFactory) CmdZombie() *cli.Command {
+	return &cli.Command{
+		Usage:       "Zombie <start|resume|report>",
+		Description: "Find Friends Who Deleted Or Blocked You",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "start",
+				Usage: "Check all friends through a temporary group",
+				Flags: []cli.Flag{yesFlag},
+				Action: func(ctx *cli.Context) error {
+					if !ctx.Bool("yes") && !confirm("Check all friends through a temporary group?") {
+						return errCanceled
+					}
+					audit, err := h.NewZombieAudit()
+					if err != nil {
+						return err
+					}
+					return runZombieAudit(audit)
+				},
+			},
+			{
+				Name:  "resume",
+				Usage: "Resume an interrupted check",
+				Action: func(ctx *cli.Context) error {
+					audit, err := helper.LoadZombieAudit()
+					if err != nil {
+						return err
+					}
+					return runZombieAudit(audit)
+				},
+			},
+			{
+				Name:  "report",
+				Usage: "Show the friends who deleted or blocked you",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "all", Aliases: []string{"a"}, Usage: "include normal friends"},
+					&cli.StringFlag{Name: "export", Aliases: []string{"o"}, Usage: "export the result to a csv `file`"},
+				},
+				Action: func(ctx *cli.Context) error {
+					audit, err := helper.LoadZombieAudit()
+					if err != nil {
+						return err
+					}
+					fmt.Println(helper.ZombieReport(audit, ctx.Bool("all")))
+					if fileName := ctx.String("export"); fileName != "" {
+						return audit.Export(fileName)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
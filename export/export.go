@@ -0,0 +1,257 @@
+// Package export writes conversations of the message store to Markdown, HTML or JSONL files.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"wx-cli/client"
+)
+
+type Format string
+
+const (
+	Markdown Format = "md"
+	HTML     Format = "html"
+	JSONL    Format = "jsonl"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(strings.ToLower(s)); format {
+	case Markdown, HTML, JSONL:
+		return format, nil
+	case "markdown":
+		return Markdown, nil
+	case "json":
+		return JSONL, nil
+	}
+	return "", fmt.Errorf("unknown format %q, use md, html or jsonl", s)
+}
+
+// Kinds are the message types that can be filtered on.
+var Kinds = []string{"text", "image", "sticker", "voice", "video", "file", "article", "system", "other"}
+
+// Kind returns the message type of msg as one of Kinds.
+func Kind(msg *client.Message) string {
+	switch {
+	case msg.IsText():
+		return "text"
+	case msg.IsPicture():
+		return "image"
+	case msg.IsSticker():
+		return "sticker"
+	case msg.IsVoice():
+		return "voice"
+	case msg.IsVideo():
+		return "video"
+	case msg.IsArticle():
+		return "article"
+	case msg.IsMedia() && msg.AppMsgType == client.AppMsgTypeAttach:
+		return "file"
+	case msg.IsSystem(), msg.IsRecalled():
+		return "system"
+	}
+	return "other"
+}
+
+// Filter selects messages by time range and kind, zero values match everything.
+type Filter struct {
+	From  time.Time
+	To    time.Time
+	Kinds []string
+}
+
+func (f Filter) Match(msg *client.Message) bool {
+	created := time.Unix(msg.CreateTime, 0)
+	if !f.From.IsZero() && created.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !created.Before(f.To) {
+		return false
+	}
+	if len(f.Kinds) == 0 {
+		return true
+	}
+	kind := Kind(msg)
+	for _, k := range f.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Record is a message with the names resolved for export.
+type Record struct {
+	*client.Message
+	Kind      string
+	Sender    string
+	Text      string
+	MediaFile string `json:",omitempty"` // path of the copied media, relative to the export directory
+}
+
+type Conversation struct {
+	Name    string
+	Records []*Record
+}
+
+// mediaExt guesses the extension of the media of msg.
+func mediaExt(msg *client.Message) string {
+	switch {
+	case msg.IsPicture():
+		return ".jpg"
+	case msg.IsSticker():
+		return ".gif"
+	case msg.IsVoice():
+		return ".mp3"
+	case msg.IsVideo():
+		return ".mp4"
+	}
+	return filepath.Ext(msg.FileName)
+}
+
+// mediaLock serializes the downloads of one media file.
+type mediaLock struct {
+	sync.Mutex
+	refs int
+}
+
+var (
+	mediaLocksMu sync.Mutex
+	mediaLocks   = make(map[string]*mediaLock)
+)
+
+// lockMedia locks path against other downloads of it and returns the unlock func.
+func lockMedia(path string) func() {
+	mediaLocksMu.Lock()
+	l, ok := mediaLocks[path]
+	if !ok {
+		l = &mediaLock{}
+		mediaLocks[path] = l
+	}
+	l.refs++
+	mediaLocksMu.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		mediaLocksMu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(mediaLocks, path)
+		}
+		mediaLocksMu.Unlock()
+	}
+}
+
+// SaveMedia downloads the media of msg into dir/media once
+// and returns its path relative to dir. Concurrent calls for the same
+// message wait for one download, which is renamed into place when complete.
+func SaveMedia(dir string, msg *client.Message) (string, error) {
+	if !msg.HasFile() {
+		return "", errors.New("message has no media")
+	}
+	name := filepath.Join("media", msg.MsgId+mediaExt(msg))
+	path := filepath.Join(dir, name)
+	defer lockMedia(path)()
+	if _, err := os.Stat(path); err == nil {
+		return name, nil
+	}
+	resp, err := msg.GetFile()
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err = os.MkdirAll(filepath.Join(dir, "media"), 0755); err != nil {
+		return "", err
+	}
+	part := path + ".part"
+	file, err := os.Create(part)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(part, path)
+	}
+	if err != nil {
+		os.Remove(part)
+		return "", err
+	}
+	return name, nil
+}
+
+// fileName turns a conversation name into a safe file name.
+func fileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+// Export writes each conversation to its own file in dir and returns the file names.
+// Media are downloaded into dir/media when withMedia is set, a failed download
+// keeps the message without media.
+func Export(dir string, format Format, conversations []*Conversation, withMedia bool) ([]string, error) {
+	if len(conversations) == 0 {
+		return nil, errors.New("no messages to export")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var files []string
+	used := make(map[string]int)
+	for _, conversation := range conversations {
+		if withMedia {
+			for _, record := range conversation.Records {
+				if !record.HasFile() {
+					continue
+				}
+				name, err := SaveMedia(dir, record.Message)
+				if err != nil {
+					fmt.Printf("download media of %s err: %v\n", record.MsgId, err)
+					continue
+				}
+				record.MediaFile = name
+			}
+		}
+		base := fileName(conversation.Name)
+		// conversations with the same name are numbered instead of overwritten
+		if used[base]++; used[base] > 1 {
+			base = fmt.Sprintf("%s_%d", base, used[base])
+		}
+		name := filepath.Join(dir, base+"."+string(format))
+		if err := writeFile(name, dir, format, conversation); err != nil {
+			return files, err
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+func writeFile(name, dir string, format Format, conversation *Conversation) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	switch format {
+	case Markdown:
+		return WriteMarkdown(file, conversation)
+	case HTML:
+		return WriteHTML(file, dir, conversation)
+	}
+	return WriteJSONL(file, conversation)
+}
@@ -0,0 +1,120 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wx-cli/client"
+)
+
+func testConversation() *Conversation {
+	text := &client.Message{MsgId: "1", MsgType: client.MsgTypeText, Content: "hello\nworld", CreateTime: 1600000000}
+	image := &client.Message{MsgId: "2", MsgType: client.MsgTypeImage, CreateTime: 1600000060}
+	return &Conversation{Name: "alice", Records: []*Record{
+		{Message: text, Kind: Kind(text), Sender: "alice", Text: text.Content},
+		{Message: image, Kind: Kind(image), Sender: "me", Text: "[image]", MediaFile: filepath.Join("media", "2.jpg")},
+	}}
+}
+
+func TestFilter(t *testing.T) {
+	msg := &client.Message{MsgType: client.MsgTypeText, CreateTime: time.Date(2020, 9, 13, 12, 0, 0, 0, time.Local).Unix()}
+	cases := []struct {
+		filter Filter
+		want   bool
+	}{
+		{Filter{}, true},
+		{Filter{From: time.Date(2020, 9, 13, 0, 0, 0, 0, time.Local)}, true},
+		{Filter{To: time.Date(2020, 9, 13, 0, 0, 0, 0, time.Local)}, false},
+		{Filter{Kinds: []string{"image", "text"}}, true},
+		{Filter{Kinds: []string{"image"}}, false},
+	}
+	for i, c := range cases {
+		if got := c.filter.Match(msg); got != c.want {
+			t.Errorf("case %d: Match = %v, want %v", i, got, c.want)
+		}
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "media"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "media", "2.jpg"), []byte("jpeg"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	conversation := testConversation()
+
+	var md bytes.Buffer
+	if err := WriteMarkdown(&md, conversation); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(md.String(), "hello  \nworld") || !strings.Contains(md.String(), "![image](media/2.jpg)") {
+		t.Errorf("unexpected markdown:\n%s", md.String())
+	}
+
+	var page bytes.Buffer
+	if err := WriteHTML(&page, dir, conversation); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(page.String(), `src="data:image/jpeg;base64,anBlZw=="`) {
+		t.Errorf("media not embedded:\n%s", page.String())
+	}
+
+	var lines bytes.Buffer
+	if err := WriteJSONL(&lines, conversation); err != nil {
+		t.Fatal(err)
+	}
+	var first map[string]interface{}
+	if err := json.Unmarshal(bytes.SplitN(lines.Bytes(), []byte("\n"), 2)[0], &first); err != nil {
+		t.Fatal(err)
+	}
+	if first["MsgId"] != "1" || first["Sender"] != "alice" || first["Conversation"] != "alice" {
+		t.Errorf("unexpected json line: %v", first)
+	}
+}
+
+func TestExportFileNames(t *testing.T) {
+	dir := t.TempDir()
+	conversations := []*Conversation{{Name: "a/b"}, {Name: "a/b"}}
+	files, err := Export(dir, JSONL, conversations, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a_b.jsonl"), filepath.Join(dir, "a_b_2.jsonl")}
+	if len(files) != 2 || files[0] != want[0] || files[1] != want[1] {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+}
+
+func TestLockMedia(t *testing.T) {
+	var inside, overlaps int32
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			unlock := lockMedia("media/1.jpg")
+			defer unlock()
+			if atomic.AddInt32(&inside, 1) > 1 {
+				atomic.AddInt32(&overlaps, 1)
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&inside, -1)
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+	if overlaps != 0 {
+		t.Errorf("%d downloads of the same media overlapped", overlaps)
+	}
+	if len(mediaLocks) != 0 {
+		t.Errorf("%d media locks left", len(mediaLocks))
+	}
+}
@@ -0,0 +1,78 @@
+package export
+
+import (
+	"encoding/base64"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"mime"
+	"path/filepath"
+	"wx-cli/util"
+)
+
+var htmlTemplate = template.Must(template.New("html").Funcs(template.FuncMap{
+	"time": util.Int64ToTimeString,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; max-width: 800px; margin: auto; background: #f5f5f5; }
+.msg { background: #fff; border-radius: 4px; margin: 8px 0; padding: 8px 12px; }
+.sender { font-weight: bold; }
+.time { color: #999; font-size: small; margin-left: 8px; }
+.text { white-space: pre-wrap; margin-top: 4px; }
+img, video { max-width: 100%; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+{{range .Messages}}<div class="msg">
+<span class="sender">{{.Sender}}</span><span class="time">{{time .CreateTime}}</span>
+{{if .Media}}{{if or (eq .Kind "image") (eq .Kind "sticker")}}<div><img src="{{.Media}}"></div>
+{{else if eq .Kind "video"}}<div><video controls src="{{.Media}}"></video></div>
+{{else if eq .Kind "voice"}}<div><audio controls src="{{.Media}}"></audio></div>
+{{else}}<div><a download="{{.FileName}}" href="{{.Media}}">{{.Text}}</a></div>
+{{end}}{{else}}<div class="text">{{.Text}}</div>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
+
+type htmlMessage struct {
+	*Record
+	Media template.URL
+}
+
+// dataURL reads the copied media into a data url so the page is self-contained.
+func dataURL(dir, name string) (template.URL, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return template.URL("data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(b)), nil
+}
+
+// WriteHTML writes a self-contained page, media copied into dir are embedded.
+func WriteHTML(w io.Writer, dir string, conversation *Conversation) error {
+	messages := make([]htmlMessage, len(conversation.Records))
+	for i, record := range conversation.Records {
+		messages[i].Record = record
+		if record.MediaFile != "" {
+			media, err := dataURL(dir, record.MediaFile)
+			if err != nil {
+				return err
+			}
+			messages[i].Media = media
+		}
+	}
+	return htmlTemplate.Execute(w, struct {
+		Name     string
+		Messages []htmlMessage
+	}{conversation.Name, messages})
+}
@@ -0,0 +1,24 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// WriteJSONL writes one json object per line with the Message fields
+// and the resolved names of Record.
+func WriteJSONL(w io.Writer, conversation *Conversation) error {
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+	for _, record := range conversation.Records {
+		line := struct {
+			*Record
+			Conversation string
+		}{record, conversation.Name}
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,28 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"wx-cli/util"
+)
+
+func WriteMarkdown(w io.Writer, conversation *Conversation) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n\n", conversation.Name)
+	for _, record := range conversation.Records {
+		fmt.Fprintf(bw, "**%s** %s\n\n", record.Sender, util.Int64ToTimeString(record.CreateTime))
+		switch {
+		case record.MediaFile != "" && (record.Kind == "image" || record.Kind == "sticker"):
+			fmt.Fprintf(bw, "![%s](%s)\n\n", record.Kind, filepath.ToSlash(record.MediaFile))
+		case record.MediaFile != "":
+			fmt.Fprintf(bw, "[%s](%s)\n\n", record.Text, filepath.ToSlash(record.MediaFile))
+		default:
+			// keep line breaks of multi-line messages
+			fmt.Fprintf(bw, "%s\n\n", strings.ReplaceAll(record.Text, "\n", "  \n"))
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,299 @@
+package helper
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+	"wx-cli/client"
+	"wx-cli/util"
+)
+
+const (
+	BroadcastPending = "Pending"
+	BroadcastSent    = "Sent"
+	BroadcastFailed  = "Failed"
+)
+
+// BroadcastTarget is a recipient of a broadcast, found by Name on every run
+// since UserName changes between sessions. Sent records the parts already
+// sent so that a retry does not send them again.
+type BroadcastTarget struct {
+	Name   string
+	Status string
+	Sent   client.BroadcastParts
+	Error  string
+	SentAt int64
+}
+
+// BroadcastJob is saved after every recipient so an interrupted broadcast can be resumed.
+type BroadcastJob struct {
+	Id        string
+	Payload   client.BroadcastPayload
+	Targets   []*BroadcastTarget
+	CreatedAt int64
+}
+
+func broadcastJobFileName(id string) string {
+	return fmt.Sprintf("%s/broadcast_%s.json", util.GetCurrentPath(), id)
+}
+
+func (j *BroadcastJob) Save() error {
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(broadcastJobFileName(j.Id), b, 0666)
+}
+
+func LoadBroadcastJob(id string) (*BroadcastJob, error) {
+	b, err := ioutil.ReadFile(broadcastJobFileName(id))
+	if err != nil {
+		return nil, err
+	}
+	var job BroadcastJob
+	if err = json.Unmarshal(b, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (j *BroadcastJob) Count(status string) int {
+	var count int
+	for _, target := range j.Targets {
+		if target.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+func (h *Helper) AddTag(tag string, users ...*client.User) {
+	names := make([]string, len(users))
+	for i, user := range users {
+		names[i] = h.GetName(user)
+	}
+	h.directory.AddTag(tag, names...)
+	h.saveDirectory()
+}
+
+func (h *Helper) RemoveTag(tag string, names ...string) {
+	h.directory.RemoveTag(tag, names...)
+	h.saveDirectory()
+}
+
+func (h *Helper) Tagged(tag string) []string {
+	return h.directory.Tagged(tag)
+}
+
+func (h *Helper) TagNames() []string {
+	return h.directory.TagNames()
+}
+
+func readTargetFile(fileName string) ([]string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name != "" && !strings.HasPrefix(name, "#") {
+			names = append(names, name)
+		}
+	}
+	return names, scanner.Err()
+}
+
+// ResolveTargets resolves specs into contacts, a spec is a name,
+// `tag:<tag>` for tagged contacts or `file:<path>` for a file of names one per line.
+// Names given directly are matched fuzzily, those of tags and files must be exact
+// since they are not typed by hand. Contacts named more than once are listed once.
+func (h *Helper) ResolveTargets(specs []string) (client.Members, error) {
+	return h.resolveTargets(specs, false)
+}
+
+// ResolveExactTargets resolves specs like ResolveTargets, but every name must be
+// exactly the name of one contact, for actions nobody confirms.
+func (h *Helper) ResolveExactTargets(specs []string) (client.Members, error) {
+	return h.resolveTargets(specs, true)
+}
+
+func (h *Helper) resolveTargets(specs []string, exact bool) (client.Members, error) {
+	type target struct {
+		name  string
+		exact bool
+	}
+	var targets []target
+	for _, spec := range specs {
+		switch {
+		case strings.HasPrefix(spec, "tag:"):
+			tagged := h.Tagged(strings.TrimPrefix(spec, "tag:"))
+			if len(tagged) == 0 {
+				return nil, fmt.Errorf("no contacts tagged %s", strings.TrimPrefix(spec, "tag:"))
+			}
+			for _, name := range tagged {
+				targets = append(targets, target{name, true})
+			}
+		case strings.HasPrefix(spec, "file:"):
+			fileNames, err := readTargetFile(strings.TrimPrefix(spec, "file:"))
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range fileNames {
+				targets = append(targets, target{name, true})
+			}
+		default:
+			targets = append(targets, target{spec, exact})
+		}
+	}
+	var users client.Members
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		find := h.FindContact
+		if target.exact {
+			find = h.findExactContact
+		}
+		user, err := find(target.name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[user.UserName] {
+			seen[user.UserName] = true
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// newBroadcastJobId returns an id from the time and reserves its file,
+// jobs started in the same second get a suffix.
+func newBroadcastJobId(now time.Time) (string, error) {
+	base := now.Format("20060102150405")
+	for n := 1; ; n++ {
+		id := base
+		if n > 1 {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		file, err := os.OpenFile(broadcastJobFileName(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return id, file.Close()
+	}
+}
+
+func (h *Helper) NewBroadcastJob(users client.Members, payload client.BroadcastPayload) (*BroadcastJob, error) {
+	now := time.Now()
+	id, err := newBroadcastJobId(now)
+	if err != nil {
+		return nil, err
+	}
+	job := &BroadcastJob{
+		Id:        id,
+		Payload:   payload,
+		CreatedAt: now.Unix(),
+	}
+	for _, user := range users {
+		// every run finds the target by this name, it must lead back to user
+		name := h.GetName(user)
+		if found, err := h.findExactContact(name); err != nil || found.UserName != user.UserName {
+			os.Remove(broadcastJobFileName(id))
+			return nil, fmt.Errorf("%s can not be told apart from other contacts by name", name)
+		}
+		job.Targets = append(job.Targets, &BroadcastTarget{
+			Name:   name,
+			Status: BroadcastPending,
+		})
+	}
+	return job, job.Save()
+}
+
+// RunBroadcast sends to the pending targets of job, and the failed ones if retryFailed.
+func (h *Helper) RunBroadcast(ctx context.Context, job *BroadcastJob, retryFailed bool, onResult func(target *BroadcastTarget)) error {
+	var users client.Members
+	// targets that lead to the same contact share its single send
+	targets := make(map[string][]*BroadcastTarget)
+	for _, target := range job.Targets {
+		if target.Status == BroadcastSent || target.Status == BroadcastFailed && !retryFailed {
+			continue
+		}
+		// the UserName of an earlier run is dead, find the contact by name again
+		user, err := h.findExactContact(target.Name)
+		if err != nil {
+			target.Status = BroadcastFailed
+			target.Error = err.Error()
+			continue
+		}
+		if _, ok := targets[user.UserName]; !ok {
+			users = append(users, user)
+		}
+		targets[user.UserName] = append(targets[user.UserName], target)
+	}
+	option := client.BroadcastOption{
+		Interval: time.Duration(h.cfg.Broadcast.Interval),
+		Jitter:   time.Duration(h.cfg.Broadcast.Jitter),
+		Sent: func(user *client.User) client.BroadcastParts {
+			var sent client.BroadcastParts
+			for _, target := range targets[user.UserName] {
+				sent.Text = sent.Text || target.Sent.Text
+				sent.File = sent.File || target.Sent.File
+			}
+			return sent
+		},
+	}
+	err := h.self.Broadcast(ctx, users, &job.Payload, option, func(result *client.BroadcastResult) {
+		for _, target := range targets[result.User.UserName] {
+			target.Sent = result.Sent
+			target.SentAt = time.Now().Unix()
+			if result.Err != nil {
+				target.Status = BroadcastFailed
+				target.Error = result.Err.Error()
+			} else {
+				target.Status = BroadcastSent
+				target.Error = ""
+			}
+		}
+		if err := job.Save(); err != nil {
+			fmt.Println("save broadcast job err:", err)
+		}
+		if onResult != nil {
+			for _, target := range targets[result.User.UserName] {
+				onResult(target)
+			}
+		}
+	})
+	if saveErr := job.Save(); err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+func (h *Helper) BroadcastReport(job *BroadcastJob) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Broadcast %s: %d sent, %d failed, %d pending\n",
+		job.Id, job.Count(BroadcastSent), job.Count(BroadcastFailed), job.Count(BroadcastPending)))
+	for _, target := range job.Targets {
+		builder.WriteString(BroadcastTargetToString(target))
+		builder.WriteString("\n")
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
+
+func BroadcastTargetToString(target *BroadcastTarget) string {
+	text := fmt.Sprintf("[%s] %s", target.Status, target.Name)
+	if target.Error != "" {
+		text = fmt.Sprintf("%s: %s", text, target.Error)
+	}
+	return text
+}
@@ -0,0 +1,91 @@
+package helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"wx-cli/client"
+	"wx-cli/storage"
+)
+
+func TestNewBroadcastJobIdSameSecond(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.Local)
+	first, err := newBroadcastJobId(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(broadcastJobFileName(first))
+	second, err := newBroadcastJobId(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(broadcastJobFileName(second))
+	if first == second {
+		t.Errorf("both jobs got id %s", first)
+	}
+}
+
+func TestExactMatches(t *testing.T) {
+	users := client.Members{
+		{UserName: "@a", NickName: "alice", RemarkName: "Alice W"},
+		{UserName: "@b", NickName: "alice2"},
+		{UserName: "@c", NickName: "Alice W"},
+	}
+	if matches := exactMatches(users, "alice"); matches.Count() != 1 || matches.First().UserName != "@a" {
+		t.Errorf("exactMatches(alice) = %v", matches)
+	}
+	if matches := exactMatches(users, "Alice W"); matches.Count() != 2 {
+		t.Errorf("exactMatches(Alice W) = %d, want both the remark and the nick name", matches.Count())
+	}
+	if matches := exactMatches(users, "ali"); matches.Count() != 0 {
+		t.Errorf("exactMatches(ali) = %d, want no fuzzy match", matches.Count())
+	}
+}
+
+// newTestHelper returns a helper offline, resolving names from contacts.
+func newTestHelper(t *testing.T, contacts ...*client.User) *Helper {
+	h := &Helper{
+		self:      &client.Self{User: &client.User{UserName: "@me"}},
+		directory: storage.NewDirectory(filepath.Join(t.TempDir(), "contacts.json")),
+	}
+	h.directory.Replace(contacts)
+	return h
+}
+
+func TestResolveTargets(t *testing.T) {
+	h := newTestHelper(t,
+		&client.User{UserName: "@a", NickName: "alice", RemarkName: "Alice W"},
+		&client.User{UserName: "@b", NickName: "bob"},
+		&client.User{UserName: "@t1", NickName: "twin"},
+		&client.User{UserName: "@t2", NickName: "twin"},
+	)
+	if users, err := h.ResolveTargets([]string{"bo"}); err != nil || users.First().UserName != "@b" {
+		t.Errorf("typed names match fuzzily: %v %v", users, err)
+	}
+	if _, err := h.ResolveExactTargets([]string{"bo"}); err == nil {
+		t.Error("exact targets matched a partial name")
+	}
+
+	fileName := filepath.Join(t.TempDir(), "targets.txt")
+	if err := ioutil.WriteFile(fileName, []byte("Alice W\nalice\n# comment\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	users, err := h.ResolveTargets([]string{"file:" + fileName, "bob"})
+	if err != nil || users.Count() != 2 || users[0].UserName != "@a" || users[1].UserName != "@b" {
+		t.Errorf("file targets = %v %v, want alice once and bob", users, err)
+	}
+	if err = ioutil.WriteFile(fileName, []byte("bo\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = h.ResolveTargets([]string{"file:" + fileName}); err == nil {
+		t.Error("file targets matched a partial name")
+	}
+
+	// a name shared by two contacts can not be found again on resume
+	twin, _ := h.directory.Get("@t1")
+	if _, err = h.NewBroadcastJob(client.Members{twin}, client.BroadcastPayload{Text: "hi"}); err == nil {
+		t.Error("broadcast job created for an ambiguous name")
+	}
+}
@@ -0,0 +1,129 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+	"wx-cli/plugins"
+)
+
+type Config struct {
+	StorageFileName string
+	HistoryFileName string
+	RulesFileName   string
+	FriendRequest   FriendRequestConfig
+	Broadcast       BroadcastConfig
+	Zombie          ZombieConfig
+	Fetch           FetchConfig
+	Plugins         []plugins.Config
+	Webhook         WebhookConfig
+	Metrics         MetricsConfig
+}
+
+// Duration is a time.Duration written as "3s" or "1m30s" in the config file.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+type FriendRequestConfig struct {
+	AutoAccept    bool
+	VerifyContent string
+	Rules         []FriendAcceptRule
+}
+
+// FriendAcceptRule matches a friend request when all of its non-empty conditions match.
+type FriendAcceptRule struct {
+	Keywords []string
+	Scenes   []int
+}
+
+// validate rejects rules that would accept every friend request,
+// a rule without conditions or with an empty keyword matches anyone.
+func (c FriendRequestConfig) validate() error {
+	for i, rule := range c.Rules {
+		if len(rule.Keywords) == 0 && len(rule.Scenes) == 0 {
+			return fmt.Errorf("FriendRequest.Rules[%d] has no Keywords or Scenes", i)
+		}
+		for _, keyword := range rule.Keywords {
+			if keyword == "" {
+				return fmt.Errorf("FriendRequest.Rules[%d] has an empty keyword", i)
+			}
+		}
+	}
+	return nil
+}
+
+type BroadcastConfig struct {
+	Interval Duration
+	Jitter   Duration
+}
+
+// ZombieConfig paces the zombie friend audit, friends are probed BatchSize at a time.
+type ZombieConfig struct {
+	BatchSize int
+	Interval  Duration
+}
+
+// FetchConfig paces fetchmembers, Concurrency requests of 50 contacts run at once
+// and start at least Interval apart.
+type FetchConfig struct {
+	Concurrency int
+	Interval    Duration
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		StorageFileName: "storage.json",
+		HistoryFileName: ".wx-cli_history",
+		RulesFileName:   "rules.json",
+		Broadcast: BroadcastConfig{
+			Interval: Duration(3 * time.Second),
+			Jitter:   Duration(2 * time.Second),
+		},
+		Zombie: ZombieConfig{
+			BatchSize: 20,
+			Interval:  Duration(30 * time.Second),
+		},
+		Fetch: FetchConfig{
+			Concurrency: 4,
+			Interval:    Duration(200 * time.Millisecond),
+		},
+	}
+}
+
+// LoadConfig reads fileName over DefaultConfig, a missing file is not an error.
+func LoadConfig(fileName string) (*Config, error) {
+	cfg := DefaultConfig()
+	b, err := ioutil.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, cfg); err != nil {
+		return nil, err
+	}
+	if err = cfg.FriendRequest.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,58 @@
+package helper
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := LoadConfig(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.StorageFileName != DefaultConfig().StorageFileName {
+		t.Error("missing config file should fall back to default")
+	}
+
+	fileName := filepath.Join(dir, "config.json")
+	content := `{"Broadcast": {"Interval": "1m30s"}, "FriendRequest": {"AutoAccept": true}}`
+	if err = ioutil.WriteFile(fileName, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfig(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(cfg.Broadcast.Interval) != 90*time.Second {
+		t.Errorf("Interval = %v, want 1m30s", time.Duration(cfg.Broadcast.Interval))
+	}
+	if time.Duration(cfg.Broadcast.Jitter) != time.Duration(DefaultConfig().Broadcast.Jitter) {
+		t.Error("unset Jitter should keep default")
+	}
+	if !cfg.FriendRequest.AutoAccept {
+		t.Error("AutoAccept not loaded")
+	}
+}
+
+func TestLoadConfigRejectsAcceptAll(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "config.json")
+	for _, rules := range []string{`[{}]`, `[{"Scenes": [30]}, {"Keywords": []}]`, `[{"Keywords": ["hi", ""]}]`} {
+		content := `{"FriendRequest": {"AutoAccept": true, "Rules": ` + rules + `}}`
+		if err := ioutil.WriteFile(fileName, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(fileName); err == nil {
+			t.Errorf("rules %s loaded, want an error", rules)
+		}
+	}
+	content := `{"FriendRequest": {"AutoAccept": true, "Rules": [{"Keywords": ["hi"]}, {"Scenes": [30]}]}}`
+	if err := ioutil.WriteFile(fileName, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(fileName); err != nil {
+		t.Error(err)
+	}
+}
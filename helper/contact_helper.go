@@ -0,0 +1,285 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"wx-cli/client"
+	"wx-cli/storage"
+)
+
+// loadDirectory loads the saved contacts. A resumed session still has their UserNames,
+// so names resolve from them without fetching, after a new login they are replaced
+// by the contacts of this session in the background.
+func (h *Helper) loadDirectory(fileName string, resumed bool) {
+	var err error
+	h.directory, err = storage.NewDirectoryFromFile(fileName)
+	if err != nil {
+		h.directory = storage.NewDirectory(fileName)
+	}
+	if resumed && h.directory.Count() > 0 {
+		// webwxinit may return contacts changed since the last session
+		h.directory.Apply(h.self.Contacts(), nil)
+		h.saveDirectory()
+		return
+	}
+	// until the contact list is fetched, lookups fall back to client.Self
+	h.directory.Replace(nil)
+	h.saveDirectory()
+	go func() {
+		contacts, err := h.sessionContacts()
+		if err != nil {
+			fmt.Println("load contacts err:", err)
+			return
+		}
+		h.directory.Replace(contacts)
+		h.saveDirectory()
+	}()
+}
+
+// sessionContacts is the contact list together with the contacts of webwxinit and webwxsync,
+// which include groups not saved to the contact list.
+func (h *Helper) sessionContacts() (client.Members, error) {
+	members, err := h.self.Members(false)
+	// copy, the members are the cache of client.Self
+	contacts := append(client.Members(nil), members...)
+	seen := make(map[string]bool, len(members))
+	for _, member := range members {
+		seen[member.UserName] = true
+	}
+	for _, contact := range h.self.Contacts() {
+		if !seen[contact.UserName] {
+			contacts = append(contacts, contact)
+		}
+	}
+	return contacts, err
+}
+
+func (h *Helper) saveDirectory() {
+	if err := h.directory.Save(); err != nil {
+		fmt.Println("save contacts err:", err)
+	}
+}
+
+func (h *Helper) onContactChanged(e client.Event) {
+	if h.directory == nil {
+		return
+	}
+	switch e := e.(type) {
+	case *client.ContactModifiedEvent:
+		h.directory.Apply(e.Users, nil)
+	case *client.ContactDeletedEvent:
+		h.directory.Apply(nil, e.Users)
+	}
+	h.saveDirectory()
+}
+
+// FetchMembers reloads the contact list and fetches the details of every contact,
+// or only the groups with their member lists if groupsOnly.
+// When some batches fail the fetched details are still saved.
+func (h *Helper) FetchMembers(groupsOnly bool, progress func(p client.DetailProgress)) error {
+	if _, err := h.self.Members(true); err != nil {
+		return err
+	}
+	option := client.DetailOption{
+		Concurrency: h.cfg.Fetch.Concurrency,
+		Interval:    time.Duration(h.cfg.Fetch.Interval),
+		Progress:    progress,
+	}
+	var fetchErr error
+	if groupsOnly {
+		fetchErr = h.self.UpdateGroupsDetail(option)
+	} else {
+		fetchErr = h.self.UpdateMembersDetail(option)
+	}
+	var batchErr *client.BatchDetailError
+	if fetchErr != nil && !errors.As(fetchErr, &batchErr) {
+		return fetchErr
+	}
+	members, err := h.sessionContacts()
+	if err != nil {
+		return err
+	}
+	h.directory.Replace(members)
+	h.saveDirectory()
+	return fetchErr
+}
+
+func (h *Helper) ContactCount() int {
+	return h.directory.Count()
+}
+
+func (h *Helper) lookupUser(username string) (*client.User, bool) {
+	if username == h.self.UserName {
+		return h.self.User, true
+	}
+	if h.directory == nil {
+		return nil, false
+	}
+	return h.directory.Get(username)
+}
+
+// localUser finds username in the directory, the contacts or the cached details without fetching it.
+func (h *Helper) localUser(username string) (*client.User, bool) {
+	if user, ok := h.lookupUser(username); ok {
+		return user, true
+	}
+	if user, ok := h.self.FindContactByUserName(username); ok {
+		return user, true
+	}
+	return h.self.CachedUserDetail(username)
+}
+
+// localGroupMember finds a member of group like localUser.
+func (h *Helper) localGroupMember(groupUserName, username string) (*client.User, bool) {
+	if user, ok := h.directory.GetMember(groupUserName, username); ok {
+		return user, true
+	}
+	if group, ok := h.self.CachedUserDetail(groupUserName); ok {
+		return group.MemberList.GetByUserName(username)
+	}
+	return nil, false
+}
+
+func (h *Helper) sender(msg *client.Message) (*client.User, error) {
+	if user, ok := h.lookupUser(msg.FromUserName); ok {
+		return user, nil
+	}
+	return msg.Sender()
+}
+
+func (h *Helper) receiver(msg *client.Message) (*client.User, error) {
+	if user, ok := h.lookupUser(msg.ToUserName); ok {
+		return user, nil
+	}
+	return msg.Receiver()
+}
+
+func (h *Helper) senderInGroup(msg *client.Message) (*client.User, error) {
+	if msg.IsSendBySelf() {
+		return h.self.User, nil
+	}
+	if msg.IsSystem() {
+		return msg.SenderInGroup()
+	}
+	if user, ok := h.directory.GetMember(msg.FromUserName, msg.SenderInGroupUserName()); ok {
+		return user, nil
+	}
+	return msg.SenderInGroup()
+}
+
+func (h *Helper) contacts() (client.Members, error) {
+	if h.directory != nil && h.directory.Count() > 0 {
+		return h.directory.Members(), nil
+	}
+	return h.self.Members(false)
+}
+
+// SearchContacts ranks friends and groups by how well they match keyword.
+func (h *Helper) SearchContacts(keyword string, limit int) (client.Members, error) {
+	members, err := h.contacts()
+	if err != nil {
+		return nil, err
+	}
+	members = members.Search(0, func(user *client.User) bool {
+		return user.IsFriend() || user.IsGroup()
+	})
+	return members.FuzzySearch(limit, keyword), nil
+}
+
+// FindContact resolves name to a single friend or group.
+func (h *Helper) FindContact(name string) (*client.User, error) {
+	members, err := h.SearchContacts(name, 0)
+	if err != nil {
+		return nil, err
+	}
+	return h.resolveName(members, name)
+}
+
+func (h *Helper) FindFriend(name string) (*client.Friend, error) {
+	user, err := h.findContact(name, (*client.User).IsFriend)
+	if err != nil {
+		return nil, err
+	}
+	return &client.Friend{User: user}, nil
+}
+
+func (h *Helper) FindGroup(name string) (*client.Group, error) {
+	user, err := h.findContact(name, (*client.User).IsGroup)
+	if err != nil {
+		return nil, err
+	}
+	return &client.Group{User: user}, nil
+}
+
+func (h *Helper) findContact(name string, cond func(user *client.User) bool) (*client.User, error) {
+	members, err := h.contacts()
+	if err != nil {
+		return nil, err
+	}
+	return h.resolveName(members.Search(0, cond).FuzzySearch(0, name), name)
+}
+
+// resolveName picks the user named name from fuzzy search results,
+// an exact name wins over fuzzy matches.
+func (h *Helper) resolveName(results client.Members, name string) (*client.User, error) {
+	if results.Count() == 0 {
+		return nil, fmt.Errorf("%w: %s", client.ErrNoSuchUserFoundError, name)
+	}
+	for _, user := range results {
+		if user.RemarkName == name || user.NickName == name || user.DisplayName == name {
+			return user, nil
+		}
+	}
+	best := results.First()
+	if results.Count() > 1 && results[1].FuzzyScore(name) == best.FuzzyScore(name) {
+		candidates := make([]string, 0, 5)
+		for _, user := range results.Search(5, func(user *client.User) bool {
+			return user.FuzzyScore(name) == best.FuzzyScore(name)
+		}) {
+			candidates = append(candidates, h.GetName(user))
+		}
+		return nil, fmt.Errorf("ambiguous name %s: %s", name, strings.Join(candidates, ", "))
+	}
+	return best, nil
+}
+
+// exactMatches returns the users whose remark, nick or display name is exactly name.
+func exactMatches(users client.Members, name string) client.Members {
+	return users.Search(0, func(user *client.User) bool {
+		return user.RemarkName == name || user.NickName == name || user.DisplayName == name
+	})
+}
+
+// findExactContact resolves name to the only friend or group with exactly that name,
+// for actions that must not guess, like resuming a broadcast.
+func (h *Helper) findExactContact(name string) (*client.User, error) {
+	members, err := h.contacts()
+	if err != nil {
+		return nil, err
+	}
+	matches := exactMatches(members.Search(0, func(user *client.User) bool {
+		return user.IsFriend() || user.IsGroup()
+	}), name)
+	switch matches.Count() {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", client.ErrNoSuchUserFoundError, name)
+	case 1:
+		return matches.First(), nil
+	}
+	return nil, fmt.Errorf("ambiguous name %s: %d contacts have it", name, matches.Count())
+}
+
+// CompleteContact returns contact names for completing prefix.
+func (h *Helper) CompleteContact(prefix string) []string {
+	results, err := h.SearchContacts(prefix, 20)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, results.Count())
+	for i, user := range results {
+		names[i] = h.GetName(user)
+	}
+	return names
+}
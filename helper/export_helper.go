@@ -0,0 +1,98 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"wx-cli/client"
+	"wx-cli/export"
+)
+
+type ExportOptions struct {
+	Name   string // conversation to export, empty for all
+	Filter export.Filter
+	Format export.Format
+	Dir    string
+	Media  bool
+}
+
+// peer returns the UserName of the conversation msg belongs to.
+func peer(msg *client.Message) string {
+	if msg.IsSendBySelf() {
+		return msg.ToUserName
+	}
+	return msg.FromUserName
+}
+
+func (h *Helper) exportSender(msg *client.Message) string {
+	if msg.IsSystem() {
+		return "[System]"
+	}
+	if msg.Category == client.CategoryGroup {
+		if user, err := h.senderInGroup(msg); err == nil {
+			return h.GetName(user)
+		}
+	}
+	sender, err := h.sender(msg)
+	if err != nil {
+		return "[Unknown]"
+	}
+	return h.GetName(sender)
+}
+
+func exportText(msg *client.Message, kind string) string {
+	switch kind {
+	case "text", "system":
+		return msg.Content
+	case "file", "article":
+		return fmt.Sprintf("[%s] %s", kind, msg.FileName)
+	}
+	return fmt.Sprintf("[%s]", kind)
+}
+
+// Conversations groups the stored messages matching opt by conversation, in the order they were received.
+func (h *Helper) Conversations(opt ExportOptions) ([]*export.Conversation, error) {
+	var only string
+	if opt.Name != "" {
+		user, err := h.FindContact(opt.Name)
+		if err != nil {
+			return nil, err
+		}
+		only = user.UserName
+	}
+	var conversations []*export.Conversation
+	byPeer := make(map[string]*export.Conversation)
+	for _, msg := range h.cache.AllMessages() {
+		if !opt.Filter.Match(msg) {
+			continue
+		}
+		username := peer(msg)
+		if only != "" && username != only {
+			continue
+		}
+		conversation, ok := byPeer[username]
+		if !ok {
+			conversation = &export.Conversation{Name: h.GetName(&client.User{UserName: username})}
+			byPeer[username] = conversation
+			conversations = append(conversations, conversation)
+		}
+		kind := export.Kind(msg)
+		conversation.Records = append(conversation.Records, &export.Record{
+			Message: msg,
+			Kind:    kind,
+			Sender:  h.exportSender(msg),
+			Text:    exportText(msg, kind),
+		})
+	}
+	return conversations, nil
+}
+
+func (h *Helper) Export(opt ExportOptions) ([]string, error) {
+	conversations, err := h.Conversations(opt)
+	if err != nil {
+		return nil, err
+	}
+	if len(conversations) == 0 {
+		return nil, errors.New("no messages match")
+	}
+	return export.Export(opt.Dir, opt.Format, conversations, opt.Media)
+}
@@ -0,0 +1,122 @@
+package helper
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"wx-cli/client"
+	"wx-cli/storage"
+	"wx-cli/util"
+)
+
+var friendRequestScenes = map[int]string{
+	1:  "QQ",
+	3:  "WeChat ID",
+	6:  "Single Chat",
+	13: "Contacts",
+	14: "Group Chat",
+	15: "Phone Number",
+	17: "Business Card",
+	30: "QR Code",
+}
+
+func friendRequestSource(scene int) string {
+	if source, ok := friendRequestScenes[scene]; ok {
+		return source
+	}
+	return fmt.Sprintf("Scene %d", scene)
+}
+
+func (h *Helper) FriendRequests(pendingOnly bool) storage.FriendRequests {
+	return h.cache.FriendRequests(pendingOnly)
+}
+
+func (h *Helper) FriendRequestToString(request *storage.FriendRequest) string {
+	info := request.Message.RecommendInfo
+	text := fmt.Sprintf("#%d [%s][%s] %s: %s (from %s)",
+		request.Id,
+		util.Int64ToTimeString(request.Message.CreateTime),
+		request.Status,
+		info.NickName,
+		info.Content,
+		friendRequestSource(info.Scene),
+	)
+	if content, err := request.Message.FriendAddMessageContent(); err == nil && content.Alias != "" {
+		text = fmt.Sprintf("%s WeChat ID: %s", text, content.Alias)
+	}
+	return text
+}
+
+func (h *Helper) AcceptFriendRequest(id int, verifyContent string) error {
+	request, err := h.cache.HandleFriendRequest(id, storage.FriendRequestAccepted)
+	if err != nil {
+		return err
+	}
+	if err = request.Message.Agree(verifyContent); err != nil {
+		// back to pending so it can be accepted again
+		h.cache.SetFriendRequestStatus(id, storage.FriendRequestPending)
+		return err
+	}
+	return nil
+}
+
+func (h *Helper) IgnoreFriendRequest(id int) error {
+	_, err := h.cache.HandleFriendRequest(id, storage.FriendRequestIgnored)
+	return err
+}
+
+func (r FriendAcceptRule) Match(info client.RecommendInfo) bool {
+	if len(r.Keywords) > 0 {
+		var matched bool
+		for _, keyword := range r.Keywords {
+			if strings.Contains(info.Content, keyword) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.Scenes) > 0 {
+		var matched bool
+		for _, scene := range r.Scenes {
+			if scene == info.Scene {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (c FriendRequestConfig) ShouldAccept(info client.RecommendInfo) bool {
+	if !c.AutoAccept {
+		return false
+	}
+	for _, rule := range c.Rules {
+		if rule.Match(info) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Helper) autoAcceptFriendRequest(msg *client.Message) {
+	if !h.cfg.FriendRequest.ShouldAccept(msg.RecommendInfo) {
+		return
+	}
+	for _, request := range h.cache.FriendRequests(true) {
+		if request.Message != msg {
+			continue
+		}
+		if err := h.AcceptFriendRequest(request.Id, h.cfg.FriendRequest.VerifyContent); err != nil {
+			log.Println("auto accept friend request err:", err)
+			return
+		}
+		log.Println("Accepted friend request from", msg.RecommendInfo.NickName)
+	}
+}
@@ -0,0 +1,33 @@
+package helper
+
+import (
+	"testing"
+	"wx-cli/client"
+)
+
+func TestFriendRequestShouldAccept(t *testing.T) {
+	cfg := FriendRequestConfig{
+		AutoAccept: true,
+		Rules: []FriendAcceptRule{
+			{Keywords: []string{"wx-cli"}},
+			{Scenes: []int{30}},
+		},
+	}
+	cases := []struct {
+		info client.RecommendInfo
+		want bool
+	}{
+		{client.RecommendInfo{Content: "I am from wx-cli", Scene: 3}, true},
+		{client.RecommendInfo{Content: "hello", Scene: 30}, true},
+		{client.RecommendInfo{Content: "hello", Scene: 3}, false},
+	}
+	for _, c := range cases {
+		if got := cfg.ShouldAccept(c.info); got != c.want {
+			t.Errorf("ShouldAccept(%+v) = %v, want %v", c.info, got, c.want)
+		}
+	}
+	cfg.AutoAccept = false
+	if cfg.ShouldAccept(cases[0].info) {
+		t.Error("ShouldAccept should be false when AutoAccept is disabled")
+	}
+}
@@ -0,0 +1,117 @@
+package helper
+
+import (
+	"fmt"
+	"wx-cli/client"
+)
+
+func (h *Helper) findGroupMember(group *client.Group, name string) (*client.User, error) {
+	members, err := group.Members(h.self)
+	if err != nil {
+		return nil, err
+	}
+	return h.resolveName(members.FuzzySearch(0, name), name)
+}
+
+func (h *Helper) FindFriends(names []string) (client.Friends, error) {
+	friends := make(client.Friends, 0, len(names))
+	for _, name := range names {
+		friend, err := h.FindFriend(name)
+		if err != nil {
+			return nil, err
+		}
+		friends = append(friends, friend)
+	}
+	return friends, nil
+}
+
+// FindGroupMembers resolves names to members of group for removing them,
+// so a name must be exactly the nick, remark or display name of one member.
+func (h *Helper) FindGroupMembers(group *client.Group, names []string) (client.Members, error) {
+	groupMembers, err := group.Members(h.self)
+	if err != nil {
+		return nil, err
+	}
+	return findExactMembers(groupMembers, names)
+}
+
+func findExactMembers(groupMembers client.Members, names []string) (client.Members, error) {
+	members := make(client.Members, 0, len(names))
+	for _, name := range names {
+		matches := exactMatches(groupMembers, name)
+		switch matches.Count() {
+		case 0:
+			return nil, fmt.Errorf("%w: no member named exactly %s", client.ErrNoSuchUserFoundError, name)
+		case 1:
+			members = append(members, matches.First())
+		default:
+			return nil, fmt.Errorf("ambiguous name %s: %d members have it", name, matches.Count())
+		}
+	}
+	return members, nil
+}
+
+// refreshGroup fetches the latest detail of group into the directory.
+func (h *Helper) refreshGroup(group *client.Group) {
+	if err := group.Detail(h.self); err != nil {
+		return
+	}
+	h.directory.Apply([]*client.User{group.User}, nil)
+	h.saveDirectory()
+}
+
+func (h *Helper) CreateGroup(topic string, friends client.Friends) (*client.Group, error) {
+	group, err := h.self.CreateGroup(topic, friends...)
+	if err != nil {
+		return nil, err
+	}
+	h.directory.Apply([]*client.User{group.User}, nil)
+	h.saveDirectory()
+	return group, nil
+}
+
+func (h *Helper) RenameGroup(group *client.Group, newName string) error {
+	if err := h.self.RenameGroup(group, newName); err != nil {
+		return err
+	}
+	h.refreshGroup(group)
+	return nil
+}
+
+func (h *Helper) InviteIntoGroup(group *client.Group, friends client.Friends) error {
+	if err := h.self.AddFriendsIntoGroup(group, friends...); err != nil {
+		return err
+	}
+	h.refreshGroup(group)
+	return nil
+}
+
+func (h *Helper) KickFromGroup(group *client.Group, members client.Members) error {
+	if err := h.self.RemoveMemberFromGroup(group, members); err != nil {
+		return err
+	}
+	h.refreshGroup(group)
+	return nil
+}
+
+func (h *Helper) GroupMembers(group *client.Group) (client.Members, error) {
+	members, err := group.Members(h.self)
+	if err != nil {
+		return nil, err
+	}
+	h.directory.Apply([]*client.User{group.User}, nil)
+	h.saveDirectory()
+	return members, nil
+}
+
+// IsGroupOwner reports whether member owns group. The web member list
+// usually leaves Uin 0, so the owner's UserName in the group detail decides.
+func (h *Helper) IsGroupOwner(group *client.Group, member *client.User) bool {
+	if group.ChatRoomOwner != "" {
+		return member.UserName == group.ChatRoomOwner
+	}
+	if member.UserName == h.self.UserName {
+		return group.IsOwner != 0
+	}
+	return group.OwnerUin != 0 && member.Uin != 0 && member.Uin == int64(group.OwnerUin)
+}
@@ -0,0 +1,56 @@
+package helper
+
+import (
+	"errors"
+	"testing"
+	"wx-cli/client"
+)
+
+func TestIsGroupOwner(t *testing.T) {
+	h := newTestHelper(t)
+	me := &client.User{UserName: "@me"}
+	alice := &client.User{UserName: "@alice"}
+	bob := &client.User{UserName: "@bob", Uin: 42}
+
+	owned := &client.Group{User: &client.User{UserName: "@@g", IsOwner: 1}}
+	if !h.IsGroupOwner(owned, me) || h.IsGroupOwner(owned, alice) {
+		t.Error("my own group: want only me as the owner")
+	}
+	// members of the web list have no Uin, the owner comes from the group detail
+	detailed := &client.Group{User: &client.User{UserName: "@@g", ChatRoomOwner: "@alice", OwnerUin: 7}}
+	if !h.IsGroupOwner(detailed, alice) || h.IsGroupOwner(detailed, me) || h.IsGroupOwner(detailed, bob) {
+		t.Error("ChatRoomOwner @alice: want only alice as the owner")
+	}
+	byUin := &client.Group{User: &client.User{UserName: "@@g", OwnerUin: 42}}
+	if !h.IsGroupOwner(byUin, bob) || h.IsGroupOwner(byUin, alice) || h.IsGroupOwner(byUin, me) {
+		t.Error("OwnerUin 42: want only bob as the owner")
+	}
+	unknown := &client.Group{User: &client.User{UserName: "@@g"}}
+	if h.IsGroupOwner(unknown, alice) || h.IsGroupOwner(unknown, me) {
+		t.Error("no owner known: want nobody as the owner")
+	}
+}
+
+func TestFindExactMembers(t *testing.T) {
+	members := client.Members{
+		{UserName: "@a", NickName: "alice"},
+		{UserName: "@b", NickName: "alice2"},
+		{UserName: "@c", NickName: "bob", DisplayName: "Bobby"},
+		{UserName: "@d", NickName: "Bobby"},
+	}
+	found, err := findExactMembers(members, []string{"alice", "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Count() != 2 || found[0].UserName != "@a" || found[1].UserName != "@c" {
+		t.Errorf("found %v, want @a and @c", found)
+	}
+	// a prefix of alice2 is not alice2
+	if _, err = findExactMembers(members, []string{"alice2", "ali"}); !errors.Is(err, client.ErrNoSuchUserFoundError) {
+		t.Errorf("partial name: err = %v, want no such user", err)
+	}
+	// a display name of one member is the nick name of another
+	if _, err = findExactMembers(members, []string{"Bobby"}); err == nil {
+		t.Error("ambiguous name resolved")
+	}
+}
@@ -5,27 +5,41 @@ import (
 	"fmt"
 	"strconv"
 	"wx-cli/client"
+	"wx-cli/metrics"
+	"wx-cli/plugins"
+	"wx-cli/rules"
+	"wx-cli/scheduler"
 	"wx-cli/storage"
 	"wx-cli/util"
+	"wx-cli/webhook"
 )
 
 type Helper struct {
-	bot   *client.Bot
-	self  *client.Self
-	cfg   *Config
-	to    *client.User
-	cache *storage.Cache
-}
-
-type Config struct {
-	StorageFileName string
+	bot       *client.Bot
+	self      *client.Self
+	cfg       *Config
+	to        *client.User
+	cache     *storage.Cache
+	directory *storage.Directory
+	scheduler *scheduler.Scheduler
+	rules     *rules.Engine
+	plugins   *plugins.Manager
+	webhooks  *webhook.Forwarder
+	registry  *metrics.Registry
+	metrics   *helperMetrics
+	session   *sessionState
 }
 
 func NewHelper(cfg *Config) *Helper {
-	return &Helper{
+	h := &Helper{
 		bot: client.NewBot(client.Desktop),
 		cfg: cfg,
 	}
+	h.bot.Events.On(h.onContactChanged, client.EventContactModified, client.EventContactDeleted)
+	h.bot.Events.On(h.onSent, client.EventMessageSent)
+	h.loadMetrics()
+	h.loadSession()
+	return h
 }
 
 func (h *Helper) BindUUIDCallback(f func(uuid string)) {
@@ -44,6 +58,11 @@ func (h *Helper) BindSyncCheckCallback(f func(resp client.SyncCheckResponse)) {
 	h.bot.SyncCheckCallback = f
 }
 
+// Subscribe subscribes to the bot events, see client.EventBus.
+func (h *Helper) Subscribe(handler func(e client.Event), option client.SubscribeOption) *client.Subscription {
+	return h.bot.Events.Subscribe(handler, option)
+}
+
 func (h *Helper) BindMessageHandler(f func(msg *client.Message)) {
 	h.bot.MessageHandler = f
 }
@@ -59,19 +78,28 @@ func (h *Helper) HotLogin() error {
 	h.self, _ = h.bot.GetCurrentUser()
 	uin := h.bot.Storage.Response.User.Uin
 	filePath := fmt.Sprintf("%s/%s", util.GetCurrentPath(), strconv.FormatInt(uin, 10))
+	h.loadDirectory(filePath+"_contacts.json", h.session.resumed())
 	h.cache, err = storage.NewCacheFromFile(filePath)
-	if err == nil {
-		return nil
+	if err != nil {
+		h.cache = storage.NewCache(filePath)
+	}
+	if err = h.loadRules(h.cfg.RulesFileName); err != nil {
+		return err
+	}
+	if err = h.loadScheduler(filePath + "_schedule.json"); err != nil {
+		return err
+	}
+	if err = h.loadWebhooks(filePath); err != nil {
+		return err
+	}
+	// plugins run as processes, start them only once nothing else can fail
+	if err = h.loadPlugins(); err != nil {
+		return err
 	}
-	h.cache = storage.NewCache(filePath)
+	h.serveMetrics()
 	return nil
 }
 
-func (h *Helper) FetchMembers() error {
-	_, err := h.self.Members(true)
-	return err
-}
-
 func (h *Helper) MemberCount() int {
 	members, err := h.self.Members(false)
 	if err != nil {
@@ -88,6 +116,11 @@ func (h *Helper) GetName(user *client.User) string {
 	if user == nil {
 		return "Unknown"
 	}
+	if len(user.DisplayName) == 0 && len(user.RemarkName) == 0 && len(user.NickName) == 0 {
+		if u, ok := h.lookupUser(user.UserName); ok {
+			user = u
+		}
+	}
 	if len(user.DisplayName) > 0 {
 		return user.DisplayName
 	}
@@ -109,6 +142,52 @@ func (h *Helper) GetFriendsName() ([]string, error) {
 	return names, nil
 }
 
+func (h *Helper) SetTo(name string) error {
+	user, err := h.FindContact(name)
+	if err != nil {
+		return err
+	}
+	h.to = user
+	return nil
+}
+
+func (h *Helper) To() *client.User {
+	return h.to
+}
+
+func (h *Helper) SendText(text string, atNames ...string) error {
+	if h.to == nil {
+		return errors.New("no target, use `to` first")
+	}
+	if len(atNames) == 0 {
+		_, err := h.self.SendTextToFriend(&client.Friend{User: h.to}, text)
+		return err
+	}
+	if !h.to.IsGroup() {
+		return errors.New("mention is only supported in group")
+	}
+	group := &client.Group{User: h.to}
+	for _, name := range atNames {
+		if name == "all" && len(atNames) > 1 {
+			return errors.New("@all can not be combined with other names")
+		}
+	}
+	if atNames[0] == "all" {
+		_, err := h.self.SendTextToGroupWithMentionAll(group, text)
+		return err
+	}
+	var members client.Members
+	for _, name := range atNames {
+		member, err := h.findGroupMember(group, name)
+		if err != nil {
+			return err
+		}
+		members = append(members, member)
+	}
+	_, err := h.self.SendTextToGroupWithMention(group, text, members...)
+	return err
+}
+
 func (h *Helper) Block() error {
 	return h.bot.Block()
 }
@@ -118,15 +197,35 @@ func (h *Helper) Done() <-chan struct{} {
 }
 
 func (h *Helper) StoreMessage(msg *client.Message) {
-	h.cache.StoreMessage(msg)
+	h.cache.StoreMessage(msg, h.searchNames(msg)...)
+	if msg.IsFriendAdd() {
+		// accepting calls the server, keep it off the sync goroutine
+		go h.autoAcceptFriendRequest(msg)
+	}
+	if h.rules != nil {
+		h.rules.Dispatch(msg)
+	}
+	if h.plugins != nil {
+		h.plugins.Publish(h.messageEvent(msg))
+	}
+	h.postWebhooks(msg)
 }
 
 func (h *Helper) AllMessages() storage.Messages {
 	return h.cache.AllMessages()
 }
 
-func (h *Helper) UnreadMessages() storage.Messages {
-	return h.cache.UnreadMessages()
+// UnreadMessages returns unread messages and the position of the first one.
+func (h *Helper) UnreadMessages() (storage.Messages, int) {
+	return h.cache.UnreadMessagesFrom()
+}
+
+func (h *Helper) MessageAt(n int) (*client.Message, error) {
+	msg, ok := h.cache.MessageAt(n)
+	if !ok {
+		return nil, fmt.Errorf("no such message #%d", n)
+	}
+	return msg, nil
 }
 
 func (h *Helper) MessageToString(msg *client.Message) string {
@@ -135,12 +234,12 @@ func (h *Helper) MessageToString(msg *client.Message) string {
 	var receiverText string
 	var err error
 
-	sender, err := msg.Sender()
+	sender, err := h.sender(msg)
 	if err != nil {
 		senderText = "[Unknown]"
 		fmt.Println(err)
 	}
-	receiver, err := msg.Receiver()
+	receiver, err := h.receiver(msg)
 	if err != nil {
 		receiverText = "[Unknown]"
 		fmt.Println(err)
@@ -158,7 +257,10 @@ func (h *Helper) MessageToString(msg *client.Message) string {
 		receiverText = fmt.Sprintf("[%s]", h.GetName(receiver))
 	case client.CategoryGroup:
 		msgType = "[G]"
-		senderInGroup, err := msg.SenderInGroup()
+		if msg.IsAt() {
+			msgType = "[G@]"
+		}
+		senderInGroup, err := h.senderInGroup(msg)
 		if err != nil {
 			if errors.Is(err, client.ErrMsgIsFromSys) {
 				senderText = fmt.Sprintf("[%s][System]", h.GetName(sender))
@@ -180,7 +282,44 @@ func (h *Helper) MessageToString(msg *client.Message) string {
 
 	createTime := msg.CreateTime
 	timeStr := util.Int64ToTimeString(createTime)
-	messageStr := HandleMessage(msg)
+	messageStr := h.messageText(msg)
 	result := fmt.Sprintf("[%s]%s%s%s:%s", timeStr, msgType, senderText, receiverText, messageStr)
 	return fmt.Sprintf("%s", result)
 }
+
+func (h *Helper) messageText(msg *client.Message) string {
+	recall, ok := h.cache.GetRecall(msg.MsgId)
+	if !ok {
+		return HandleMessage(msg)
+	}
+	if recall.Message != msg {
+		return fmt.Sprintf("%s[Recalled at %s]", HandleMessage(msg), util.Int64ToTimeString(recall.RecalledAt))
+	}
+	return fmt.Sprintf("recalled: %s", h.recallText(recall))
+}
+
+func (h *Helper) recallText(recall *storage.Recall) string {
+	if recall.Original == nil {
+		return recall.ReplaceMsg
+	}
+	return HandleMessage(recall.Original)
+}
+
+func (h *Helper) RecentRecalls(count int) storage.Recalls {
+	return h.cache.RecentRecalls(count)
+}
+
+func (h *Helper) RecallToString(recall *storage.Recall) string {
+	timeStr := util.Int64ToTimeString(recall.RecalledAt)
+	if recall.Original == nil {
+		return fmt.Sprintf("[%s]recalled: %s", timeStr, recall.ReplaceMsg)
+	}
+	return fmt.Sprintf("[%s]recalled: %s", timeStr, h.MessageToString(recall.Original))
+}
+
+// Forward forwards the received msg to each target in turn, uploading its media once,
+// the returned errors are indexed like targets.
+func (h *Helper) Forward(msg *client.Message, targets []*client.User) []error {
+	_, errs := h.self.ForwardReceivedMessageToEach(msg, targets...)
+	return errs
+}
@@ -0,0 +1,225 @@
+package helper
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"wx-cli/client"
+	"wx-cli/export"
+	"wx-cli/metrics"
+)
+
+// MetricsConfig serves the metrics in the Prometheus format at http://<Addr>/metrics,
+// Addr is like 127.0.0.1:9100 and empty for no endpoint.
+type MetricsConfig struct {
+	Addr string
+}
+
+// mediaEndpoints are the requests counted as media downloads.
+var mediaEndpoints = map[string]bool{
+	"webwxgetmsgimg": true,
+	"webwxgetvoice":  true,
+	"webwxgetvideo":  true,
+	"webwxgetmedia":  true,
+}
+
+type helperMetrics struct {
+	requests         *metrics.Counter
+	requestErrors    *metrics.Counter
+	requestDuration  *metrics.Histogram
+	syncChecks       *metrics.Counter
+	syncErrors       *metrics.Counter
+	messages         *metrics.Counter
+	downloads        *metrics.Counter
+	downloadErrors   *metrics.Counter
+	downloadBytes    *metrics.Counter
+	downloadDuration *metrics.Histogram
+}
+
+func (h *Helper) loadMetrics() {
+	r := metrics.NewRegistry()
+	h.registry = r
+	h.metrics = &helperMetrics{
+		requests:         r.Counter("wx_http_requests_total", "Requests to the wechat api by endpoint and status code.", "endpoint", "code"),
+		requestErrors:    r.Counter("wx_http_request_errors_total", "Requests that failed or returned a non-2xx status.", "endpoint"),
+		requestDuration:  r.Histogram("wx_http_request_duration_seconds", "Latency of the requests to the wechat api.", nil, "endpoint"),
+		syncChecks:       r.Counter("wx_synccheck_total", "Synccheck responses by retcode and selector.", "retcode", "selector"),
+		syncErrors:       r.Counter("wx_sync_errors_total", "Errors of the sync loop, retry is whether syncing went on.", "retry"),
+		messages:         r.Counter("wx_messages_total", "Received messages by type.", "type"),
+		downloads:        r.Counter("wx_media_downloads_total", "Media downloads by endpoint.", "endpoint"),
+		downloadErrors:   r.Counter("wx_media_download_errors_total", "Media downloads that failed.", "endpoint"),
+		downloadBytes:    r.Counter("wx_media_download_bytes_total", "Bytes of media downloaded.", "endpoint"),
+		downloadDuration: r.Histogram("wx_media_download_duration_seconds", "Time to download a media until the body is closed.", nil, "endpoint"),
+	}
+	r.Func("wx_dispatch_handler_calls_total", "Calls of the rule handlers.", metrics.TypeCounter, h.dispatchSamples(func(s client.HandlerStats) float64 {
+		return float64(s.Calls)
+	}))
+	r.Func("wx_dispatch_handler_panics_total", "Panics of the rule handlers.", metrics.TypeCounter, h.dispatchSamples(func(s client.HandlerStats) float64 {
+		return float64(s.Panics)
+	}))
+	r.Func("wx_dispatch_handler_seconds_total", "Time spent in the rule handlers.", metrics.TypeCounter, h.dispatchSamples(func(s client.HandlerStats) float64 {
+		return s.Total.Seconds()
+	}))
+	r.Func("wx_detail_cache_requests_total", "Contact detail lookups by result.", metrics.TypeCounter, func() []metrics.Sample {
+		if h.self == nil {
+			return nil
+		}
+		stats := h.self.DetailCacheStats()
+		return []metrics.Sample{
+			{Labels: []metrics.Label{{Name: "result", Value: "hit"}}, Value: float64(stats.Hits)},
+			{Labels: []metrics.Label{{Name: "result", Value: "miss"}}, Value: float64(stats.Misses)},
+			{Labels: []metrics.Label{{Name: "result", Value: "coalesced"}}, Value: float64(stats.Coalesced)},
+		}
+	})
+
+	h.bot.Caller.Client.RequestObserver = h.observeRequest
+	h.bot.Events.On(h.observeEvent, client.EventSyncCheck, client.EventMessage, client.EventError)
+}
+
+func (h *Helper) dispatchSamples(value func(s client.HandlerStats) float64) func() []metrics.Sample {
+	return func() []metrics.Sample {
+		if h.rules == nil {
+			return nil
+		}
+		var samples []metrics.Sample
+		for _, stats := range h.rules.Metrics() {
+			samples = append(samples, metrics.Sample{
+				Labels: []metrics.Label{{Name: "handler", Value: stats.Name}},
+				Value:  value(stats),
+			})
+		}
+		return samples
+	}
+}
+
+// serveMetrics starts the /metrics endpoint if configured.
+func (h *Helper) serveMetrics() {
+	if h.cfg.Metrics.Addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", h.registry.Handler())
+	go func() {
+		if err := http.ListenAndServe(h.cfg.Metrics.Addr, mux); err != nil {
+			fmt.Println("metrics server err:", err)
+		}
+	}()
+}
+
+func (h *Helper) observeRequest(endpoint string, elapsed time.Duration, resp *http.Response, err error) {
+	m := h.metrics
+	code := "error"
+	if resp != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	m.requests.Inc(endpoint, code)
+	m.requestDuration.Observe(elapsed.Seconds(), endpoint)
+	failed := err != nil || resp.StatusCode/100 != 2
+	if failed {
+		m.requestErrors.Inc(endpoint)
+	}
+	if !mediaEndpoints[endpoint] {
+		return
+	}
+	m.downloads.Inc(endpoint)
+	if failed {
+		m.downloadErrors.Inc(endpoint)
+		return
+	}
+	start := time.Now().Add(-elapsed)
+	resp.Body = &countingBody{ReadCloser: resp.Body, onClose: func(n int64) {
+		m.downloadBytes.Add(float64(n), endpoint)
+		m.downloadDuration.Observe(time.Since(start).Seconds(), endpoint)
+	}}
+}
+
+// countingBody counts the bytes read from a response body until it is closed.
+type countingBody struct {
+	io.ReadCloser
+	n       int64
+	onClose func(n int64)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	if b.onClose != nil {
+		b.onClose(b.n)
+		b.onClose = nil
+	}
+	return b.ReadCloser.Close()
+}
+
+func (h *Helper) observeEvent(e client.Event) {
+	switch e := e.(type) {
+	case *client.SyncCheckEvent:
+		h.metrics.syncChecks.Inc(e.Response.RetCode, e.Response.Selector)
+	case *client.MessageEvent:
+		h.metrics.messages.Inc(export.Kind(e.Message))
+	case *client.ErrorEvent:
+		h.metrics.syncErrors.Inc(strconv.FormatBool(e.Retry))
+	}
+}
+
+// Stats returns the metrics whose name contains filter.
+func (h *Helper) Stats(filter string) []metrics.Family {
+	var families []metrics.Family
+	for _, f := range h.registry.Families() {
+		if strings.Contains(f.Name, filter) {
+			families = append(families, f)
+		}
+	}
+	return families
+}
+
+// WriteStats writes the metrics in the Prometheus text format.
+func (h *Helper) WriteStats(w io.Writer, filter string) error {
+	return metrics.Write(w, h.Stats(filter))
+}
+
+func labelsText(labels []metrics.Label) string {
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Name + "=" + l.Value
+	}
+	return strings.Join(parts, " ")
+}
+
+// FamilyToString prints a metric with one sample per line, histograms as count and average.
+func FamilyToString(f metrics.Family) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s: %s", f.Name, f.Help))
+	samples := append([]metrics.Sample(nil), f.Samples...)
+	if f.Type == metrics.TypeHistogram {
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].Count > samples[j].Count })
+	} else {
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].Value > samples[j].Value })
+	}
+	for _, s := range samples {
+		builder.WriteString("\n  ")
+		if labels := labelsText(s.Labels); labels != "" {
+			builder.WriteString(labels + " ")
+		}
+		if f.Type == metrics.TypeHistogram {
+			var avg time.Duration
+			if s.Count > 0 {
+				avg = time.Duration(s.Sum / float64(s.Count) * float64(time.Second))
+			}
+			builder.WriteString(fmt.Sprintf("count=%d avg=%s", s.Count, avg.Round(time.Millisecond)))
+		} else {
+			builder.WriteString(strconv.FormatFloat(s.Value, 'f', -1, 64))
+		}
+	}
+	if len(samples) == 0 {
+		builder.WriteString("\n  (none)")
+	}
+	return builder.String()
+}
@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestObserveRequest(t *testing.T) {
+	h := NewHelper(DefaultConfig())
+	h.observeRequest("synccheck", 20*time.Millisecond, &http.Response{StatusCode: 200}, nil)
+	h.observeRequest("webwxsync", time.Second, nil, errors.New("timeout"))
+	resp := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("12345"))}
+	h.observeRequest("webwxgetmsgimg", 10*time.Millisecond, resp, nil)
+	if _, err := ioutil.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	var b strings.Builder
+	if err := h.WriteStats(&b, "wx_"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`wx_http_requests_total{endpoint="synccheck",code="200"} 1`,
+		`wx_http_requests_total{endpoint="webwxsync",code="error"} 1`,
+		`wx_http_request_errors_total{endpoint="webwxsync"} 1`,
+		`wx_media_download_bytes_total{endpoint="webwxgetmsgimg"} 5`,
+		`wx_media_download_duration_seconds_count{endpoint="webwxgetmsgimg"} 1`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %s in\n%s", want, b.String())
+		}
+	}
+
+	families := h.Stats("wx_http_request_duration")
+	if len(families) != 1 {
+		t.Fatalf("Stats = %d families, want 1", len(families))
+	}
+	text := FamilyToString(families[0])
+	if !strings.Contains(text, "endpoint=webwxsync count=1 avg=1s") {
+		t.Errorf("FamilyToString =\n%s", text)
+	}
+}
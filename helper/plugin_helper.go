@@ -0,0 +1,118 @@
+package helper
+
+import (
+	"fmt"
+	"wx-cli/client"
+	"wx-cli/export"
+	"wx-cli/plugins"
+	"wx-cli/util"
+)
+
+// pluginAPI is the API plugins call, names are resolved like the commands do.
+type pluginAPI struct {
+	h *Helper
+}
+
+func (a pluginAPI) contact(user *client.User) *plugins.Contact {
+	return &plugins.Contact{
+		UserName:   user.UserName,
+		Name:       a.h.GetName(user),
+		NickName:   user.NickName,
+		RemarkName: user.RemarkName,
+		IsGroup:    user.IsGroup(),
+	}
+}
+
+// Send sends text to the friend or group named exactly to, plugins run unattended
+// so a name that is missing or shared by several contacts is an error.
+func (a pluginAPI) Send(to, text string) error {
+	user, err := a.h.findExactContact(to)
+	if err != nil {
+		return err
+	}
+	_, err = a.h.self.SendTextToFriend(&client.Friend{User: user}, text)
+	return err
+}
+
+func (a pluginAPI) Reply(msgId, text string) error {
+	msg, ok := a.h.cache.GetMessage(msgId)
+	if !ok {
+		return fmt.Errorf("no such message %s", msgId)
+	}
+	_, err := msg.ReplyText(text)
+	return err
+}
+
+func (a pluginAPI) SessionStatus() (interface{}, error) {
+	return a.h.Status(), nil
+}
+
+func (a pluginAPI) FindContact(name string) (*plugins.Contact, error) {
+	user, err := a.h.FindContact(name)
+	if err != nil {
+		return nil, err
+	}
+	return a.contact(user), nil
+}
+
+func (a pluginAPI) SearchContacts(keyword string, limit int) ([]*plugins.Contact, error) {
+	users, err := a.h.SearchContacts(keyword, limit)
+	if err != nil {
+		return nil, err
+	}
+	contacts := make([]*plugins.Contact, len(users))
+	for i, user := range users {
+		contacts[i] = a.contact(user)
+	}
+	return contacts, nil
+}
+
+func (h *Helper) messageEvent(msg *client.Message) *plugins.MessageEvent {
+	kind := export.Kind(msg)
+	event := &plugins.MessageEvent{
+		MsgId:      msg.MsgId,
+		Type:       kind,
+		Sender:     h.exportSender(msg),
+		Text:       exportText(msg, kind),
+		At:         msg.IsAt(),
+		IsSelf:     msg.IsSendBySelf(),
+		CreateTime: msg.CreateTime,
+	}
+	if msg.Category == client.CategoryGroup {
+		event.Group = h.GetName(&client.User{UserName: peer(msg)})
+	}
+	return event
+}
+
+func (h *Helper) loadPlugins() error {
+	manager, err := plugins.NewManager(h.cfg.Plugins, pluginAPI{h: h}, util.GetCurrentPath()+"/plugins")
+	if err != nil {
+		return err
+	}
+	h.plugins = manager
+	manager.StartAll()
+	return nil
+}
+
+func (h *Helper) Plugins() []plugins.Status {
+	return h.plugins.Status()
+}
+
+func (h *Helper) StartPlugin(name string) error {
+	return h.plugins.Start(name)
+}
+
+func (h *Helper) StopPlugin(name string) error {
+	return h.plugins.Stop(name)
+}
+
+func (h *Helper) RestartPlugin(name string) error {
+	return h.plugins.Restart(name)
+}
+
+// Close stops the plugins.
+func (h *Helper) Close() {
+	if h.plugins != nil {
+		h.plugins.StopAll()
+	}
+}
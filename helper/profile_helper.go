@@ -0,0 +1,78 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"wx-cli/client"
+)
+
+// updateContact applies f to a copy of user and stores the copy into the directory
+// and the contacts of client.Self, so readers never see a half updated user.
+func (h *Helper) updateContact(user *client.User, f func(user *client.User) error) (*client.User, error) {
+	u := *user
+	if err := f(&u); err != nil {
+		return nil, err
+	}
+	h.self.UpdateContact(&u)
+	h.directory.Apply([]*client.User{&u}, nil)
+	h.saveDirectory()
+	if h.to != nil && h.to.UserName == u.UserName {
+		h.to = &u
+	}
+	return &u, nil
+}
+
+func (h *Helper) SetRemarkName(friend *client.Friend, remarkName string) error {
+	_, err := h.updateContact(friend.User, func(user *client.User) error {
+		return h.self.SetRemarkNameToFriend(&client.Friend{User: user}, remarkName)
+	})
+	return err
+}
+
+func (h *Helper) Pin(user *client.User) error {
+	_, err := h.updateContact(user, h.self.PinUser)
+	return err
+}
+
+func (h *Helper) UnPin(user *client.User) error {
+	_, err := h.updateContact(user, h.self.UnPinUser)
+	return err
+}
+
+// Profile returns the detail of user, fetching it when the directory lacks it.
+func (h *Helper) Profile(user *client.User) (*client.User, error) {
+	if user.Signature != "" || user.Province != "" || user.IsGroup() {
+		return user, nil
+	}
+	return h.updateContact(user, func(user *client.User) error {
+		return user.Detail(h.self)
+	})
+}
+
+func sexText(sex int) string {
+	switch sex {
+	case client.MALE:
+		return "Male"
+	case client.FEMALE:
+		return "Female"
+	}
+	return "Unknown"
+}
+
+func (h *Helper) ProfileToString(user *client.User) string {
+	var builder strings.Builder
+	line := func(key string, value interface{}) {
+		builder.WriteString(fmt.Sprintf("%-10s%v\n", key+":", value))
+	}
+	line("NickName", user.NickName)
+	line("Remark", user.RemarkName)
+	line("WeChat ID", user.Alias)
+	line("Sex", sexText(user.Sex))
+	line("Region", strings.TrimSpace(user.Province+" "+user.City))
+	line("Signature", user.Signature)
+	line("Pinned", user.IsPin())
+	if user.IsGroup() {
+		line("Members", user.MemberCount)
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
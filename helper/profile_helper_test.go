@@ -0,0 +1,42 @@
+package helper
+
+import (
+	"errors"
+	"testing"
+	"wx-cli/client"
+)
+
+func TestUpdateContact(t *testing.T) {
+	h := newTestHelper(t, &client.User{UserName: "@a", NickName: "alice"})
+	user, _ := h.directory.Get("@a")
+	h.to = user
+	updated, err := h.updateContact(user, func(user *client.User) error {
+		user.RemarkName = "Alice W"
+		return nil
+	})
+	if err != nil || updated.RemarkName != "Alice W" {
+		t.Fatalf("updateContact = %v, %v", updated, err)
+	}
+	if user.RemarkName != "" {
+		t.Error("the user passed in was changed")
+	}
+	if got, _ := h.directory.Get("@a"); got.RemarkName != "Alice W" {
+		t.Error("directory not updated")
+	}
+	if got, _ := h.self.FindContactByUserName("@a"); got == nil || got.RemarkName != "Alice W" {
+		t.Error("contacts of the session not updated")
+	}
+	if h.GetName(&client.User{UserName: "@a"}) != "Alice W" || h.to.RemarkName != "Alice W" {
+		t.Error("names still show the old remark")
+	}
+
+	if _, err = h.updateContact(updated, func(user *client.User) error {
+		user.RemarkName = "lost"
+		return errors.New("rejected")
+	}); err == nil {
+		t.Fatal("error not returned")
+	}
+	if got, _ := h.directory.Get("@a"); got.RemarkName != "Alice W" {
+		t.Error("failed update applied")
+	}
+}
@@ -0,0 +1,101 @@
+package helper
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"wx-cli/client"
+	"wx-cli/rules"
+)
+
+// ruleEnv carries out the actions of rules through the helper.
+type ruleEnv struct {
+	h *Helper
+}
+
+func (e ruleEnv) Sample(msg *client.Message) *rules.Sample {
+	// never react to my own messages, a reply could trigger itself
+	if msg.IsSendBySelf() {
+		return nil
+	}
+	event := e.h.messageEvent(msg)
+	return &rules.Sample{
+		Sender: event.Sender,
+		Group:  event.Group,
+		Text:   event.Text,
+		Type:   event.Type,
+		At:     event.At,
+		Time:   time.Unix(event.CreateTime, 0),
+	}
+}
+
+func (e ruleEnv) Reply(msg *client.Message, text string) error {
+	_, err := msg.ReplyText(text)
+	return err
+}
+
+func (e ruleEnv) peer(msg *client.Message) *client.User {
+	if user, ok := e.h.lookupUser(peer(msg)); ok {
+		return user
+	}
+	return &client.User{UserName: peer(msg)}
+}
+
+func (e ruleEnv) ReplyFile(msg *client.Message, filePath string) error {
+	_, err := e.h.self.SendFileByTypeToUser(e.peer(msg), filePath)
+	return err
+}
+
+// Forward sends msg on to targets, which must be exact names since nobody confirms them.
+func (e ruleEnv) Forward(msg *client.Message, targets []string) error {
+	users, err := e.h.ResolveExactTargets(targets)
+	if err != nil {
+		return err
+	}
+	var messages []string
+	for i, err := range e.h.Forward(msg, users) {
+		if err != nil {
+			messages = append(messages, e.h.GetName(users[i])+": "+err.Error())
+		}
+	}
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// Tag tags the friend or group the message comes from.
+func (e ruleEnv) Tag(msg *client.Message, tag string) error {
+	e.h.AddTag(tag, e.peer(msg))
+	return nil
+}
+
+func (h *Helper) loadRules(fileName string) error {
+	engine, err := rules.NewEngine(fileName, ruleEnv{h: h})
+	if err != nil {
+		return err
+	}
+	h.rules = engine
+	go engine.Watch(h.Done())
+	return nil
+}
+
+func (h *Helper) Rules() []*rules.Rule {
+	return h.rules.Rules()
+}
+
+func (h *Helper) ReloadRules() error {
+	return h.rules.Reload()
+}
+
+func (h *Helper) EnableRule(name string) error {
+	return h.rules.SetDisabled(name, false)
+}
+
+func (h *Helper) DisableRule(name string) error {
+	return h.rules.SetDisabled(name, true)
+}
+
+func (h *Helper) TestRules(sample *rules.Sample) []*rules.TestResult {
+	return h.rules.Test(sample)
+}
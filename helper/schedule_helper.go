@@ -0,0 +1,101 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"wx-cli/client"
+	"wx-cli/scheduler"
+)
+
+func (h *Helper) loadScheduler(fileName string) error {
+	s, err := scheduler.New(fileName, h.sendScheduledJob)
+	if err != nil {
+		return err
+	}
+	h.scheduler = s
+	go s.Run(h.Done())
+	return nil
+}
+
+// sendScheduledJob resolves the target by name when the job runs,
+// since UserName changes between sessions. Nobody is there to confirm a guess,
+// so the run fails unless exactly one contact has the name.
+func (h *Helper) sendScheduledJob(job *scheduler.Job) error {
+	user, err := h.findExactContact(job.Target)
+	if err != nil {
+		return err
+	}
+	if job.Text != "" {
+		if _, err = h.self.SendTextToFriend(&client.Friend{User: user}, job.Text); err != nil {
+			return err
+		}
+	}
+	if job.FilePath != "" {
+		if _, err = h.self.SendFileByTypeToUser(user, job.FilePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Helper) AddSchedule(job *scheduler.Job) (*scheduler.Job, error) {
+	user, err := h.FindContact(job.Target)
+	if err != nil {
+		return nil, err
+	}
+	job.Target = h.GetName(user)
+	// the runs look the name up exactly, refuse a name they could not resolve
+	if _, err = h.findExactContact(job.Target); err != nil {
+		return nil, err
+	}
+	return h.scheduler.Add(job)
+}
+
+func (h *Helper) EditSchedule(id int, f func(job *scheduler.Job) error) (*scheduler.Job, error) {
+	return h.scheduler.Edit(id, f)
+}
+
+func (h *Helper) CancelSchedule(id int) error {
+	return h.scheduler.Cancel(id)
+}
+
+func (h *Helper) Schedules() []*scheduler.Job {
+	return h.scheduler.List()
+}
+
+func (h *Helper) Schedule(id int) (*scheduler.Job, error) {
+	job, ok := h.scheduler.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("no such job #%d", id)
+	}
+	return job, nil
+}
+
+func ScheduleToString(job *scheduler.Job) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d -> %s", job.Id, job.Target)
+	if job.IsCron() {
+		fmt.Fprintf(&b, " [%s]", job.Cron)
+	}
+	if job.Done {
+		b.WriteString(" done")
+	} else {
+		fmt.Fprintf(&b, " next %s", time.Unix(job.NextRun, 0).Format("2006-01-02 15:04"))
+	}
+	if job.Text != "" {
+		fmt.Fprintf(&b, " text: %s", job.Text)
+	}
+	if job.FilePath != "" {
+		fmt.Fprintf(&b, " file: %s", job.FilePath)
+	}
+	return b.String()
+}
+
+func RunToString(run scheduler.Run) string {
+	result := "ok"
+	if run.Error != "" {
+		result = "failed: " + run.Error
+	}
+	return fmt.Sprintf("%s %s", time.Unix(run.Time, 0).Format("2006-01-02 15:04:05"), result)
+}
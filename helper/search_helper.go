@@ -0,0 +1,145 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"wx-cli/client"
+	"wx-cli/export"
+	"wx-cli/storage"
+	"wx-cli/util"
+)
+
+const (
+	highlightStart = "\033[1;31m"
+	highlightEnd   = "\033[0m"
+	snippetRadius  = 20
+)
+
+type SearchOptions struct {
+	Query        string
+	Conversation string // name of the friend or group
+	Sender       string // part of the sender name
+	Filter       export.Filter
+	Limit        int
+}
+
+func (opt SearchOptions) filtered() bool {
+	f := opt.Filter
+	return opt.Conversation != "" || opt.Sender != "" || !f.From.IsZero() || !f.To.IsZero() || len(f.Kinds) > 0
+}
+
+// searchNames are the names indexed with msg besides its content.
+// It runs on the sync goroutine, so the names only come from the directory
+// and the cached details, a contact missing there is not fetched.
+func (h *Helper) searchNames(msg *client.Message) []string {
+	var names []string
+	for _, username := range []string{msg.FromUserName, msg.ToUserName} {
+		if user, ok := h.localUser(username); ok {
+			names = append(names, h.GetName(user))
+		}
+	}
+	if msg.Category == client.CategoryGroup && !msg.IsSendBySelf() {
+		if user, ok := h.localGroupMember(msg.FromUserName, msg.SenderInGroupUserName()); ok {
+			names = append(names, h.GetName(user))
+		}
+	}
+	return names
+}
+
+// Search returns the newest messages matching opt first.
+// The query may be empty to list the messages of the other options.
+func (h *Helper) Search(opt SearchOptions) ([]*storage.SearchResult, error) {
+	if strings.TrimSpace(opt.Query) == "" && !opt.filtered() {
+		return nil, errors.New("keywords or a filter required")
+	}
+	var only string
+	if opt.Conversation != "" {
+		user, err := h.FindContact(opt.Conversation)
+		if err != nil {
+			return nil, err
+		}
+		only = user.UserName
+	}
+	sender := strings.ToLower(opt.Sender)
+	results := h.cache.Search(opt.Query)
+	var matched []*storage.SearchResult
+	for i := len(results) - 1; i >= 0; i-- {
+		msg := results[i].Message
+		if !opt.Filter.Match(msg) || only != "" && peer(msg) != only {
+			continue
+		}
+		if sender != "" && !strings.Contains(strings.ToLower(h.exportSender(msg)), sender) {
+			continue
+		}
+		matched = append(matched, results[i])
+		if opt.Limit > 0 && len(matched) == opt.Limit {
+			break
+		}
+	}
+	return matched, nil
+}
+
+// Snippet cuts text around the first term found and highlights every term.
+func Snippet(text string, terms []string) string {
+	runes := []rune(strings.ReplaceAll(text, "\n", " "))
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	highlighted := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		t := []rune(strings.ToLower(term))
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) != string(t) {
+				continue
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+			for j := i; j < i+len(t); j++ {
+				highlighted[j] = true
+			}
+		}
+	}
+	start, end := 0, len(runes)
+	if first > snippetRadius {
+		start = first - snippetRadius
+	}
+	if end-first > snippetRadius*3 && first >= 0 {
+		end = first + snippetRadius*3
+	}
+	var builder strings.Builder
+	if start > 0 {
+		builder.WriteString("...")
+	}
+	for i := start; i < end; i++ {
+		if highlighted[i] && (i == start || !highlighted[i-1]) {
+			builder.WriteString(highlightStart)
+		}
+		builder.WriteRune(runes[i])
+		if highlighted[i] && (i == end-1 || !highlighted[i+1]) {
+			builder.WriteString(highlightEnd)
+		}
+	}
+	if end < len(runes) {
+		builder.WriteString("...")
+	}
+	return builder.String()
+}
+
+func (h *Helper) SearchResultToString(result *storage.SearchResult, query string) string {
+	msg := result.Message
+	conversation := h.GetName(&client.User{UserName: peer(msg)})
+	text := result.Text
+	if text == "" {
+		text = exportText(msg, export.Kind(msg))
+	}
+	return fmt.Sprintf("#%d [%s][%s] %s: %s", result.N, util.Int64ToTimeString(msg.CreateTime),
+		conversation, h.exportSender(msg), Snippet(text, strings.Fields(query)))
+}
@@ -0,0 +1,42 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+	"wx-cli/client"
+	"wx-cli/export"
+	"wx-cli/storage"
+)
+
+func TestSnippet(t *testing.T) {
+	got := Snippet("明天上午9点开会, Meeting at 9", []string{"开会", "meeting"})
+	want := "明天上午9点" + highlightStart + "开会" + highlightEnd + ", " + highlightStart + "Meeting" + highlightEnd + " at 9"
+	if got != want {
+		t.Errorf("Snippet = %q, want %q", got, want)
+	}
+
+	long := strings.Repeat("a", 50) + "key" + strings.Repeat("b", 100)
+	got = Snippet(long, []string{"key"})
+	if !strings.HasPrefix(got, "..."+strings.Repeat("a", snippetRadius)+highlightStart) || !strings.HasSuffix(got, "...") {
+		t.Errorf("Snippet not cut around the match: %q", got)
+	}
+}
+
+func TestSearchWithoutQuery(t *testing.T) {
+	h := newTestHelper(t)
+	h.cache = storage.NewCache("")
+	h.cache.StoreMessage(&client.Message{MsgId: "1", MsgType: client.MsgTypeText, Content: "hello"})
+	h.cache.StoreMessage(&client.Message{MsgId: "2", MsgType: client.MsgTypeImage})
+	h.cache.StoreMessage(&client.Message{MsgId: "3", MsgType: client.MsgTypeText, Content: "bye"})
+
+	if _, err := h.Search(SearchOptions{Query: " "}); err == nil {
+		t.Error("search without query and filters listed every message")
+	}
+	results, err := h.Search(SearchOptions{Filter: export.Filter{Kinds: []string{"text"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Message.MsgId != "3" || results[1].Message.MsgId != "1" {
+		t.Errorf("got %d results, want the text messages newest first", len(results))
+	}
+}
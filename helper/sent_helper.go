@@ -0,0 +1,74 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"wx-cli/client"
+	"wx-cli/storage"
+	"wx-cli/util"
+)
+
+func (h *Helper) onSent(e client.Event) {
+	msg := e.(*client.MessageSentEvent).Message
+	h.cache.StoreSentMessage(msg, h.searchNames(msg.Message())...)
+}
+
+func (h *Helper) RecentSent(count int) storage.SentMessages {
+	return h.cache.RecentSent(count)
+}
+
+// Revocable returns the last count sent messages that can still be revoked, oldest first.
+func (h *Helper) Revocable(count int) storage.SentMessages {
+	var revocable storage.SentMessages
+	sent := h.cache.RecentSent(0)
+	for i := len(sent) - 1; i >= 0 && len(revocable) < count; i-- {
+		if !sent[i].Revoked && sent[i].Message.CanRevoke() {
+			revocable = append(storage.SentMessages{sent[i]}, revocable...)
+		}
+	}
+	return revocable
+}
+
+func (h *Helper) Revoke(sent *storage.Sent) error {
+	if sent.Revoked {
+		return errors.New("already revoked")
+	}
+	if !sent.Message.CanRevoke() {
+		return errors.New("revoke window expired")
+	}
+	if err := sent.Message.Revoke(); err != nil {
+		return err
+	}
+	h.cache.SetRevoked(sent.Id)
+	return nil
+}
+
+func sentContent(msg *client.SentMessage) string {
+	switch msg.Type {
+	case client.MsgTypeText:
+		return msg.Content
+	case client.MsgTypeImage:
+		return "[Image]"
+	case client.MsgTypeVideo:
+		return "[Video]"
+	case client.AppMessage:
+		return "[File]"
+	}
+	return fmt.Sprintf("[%d]", msg.Type)
+}
+
+func (h *Helper) SentToString(sent *storage.Sent) string {
+	msg := sent.Message
+	receiver := h.GetName(&client.User{UserName: msg.ToUserName})
+	var state string
+	switch remaining := msg.RevokeRemaining(); {
+	case sent.Revoked:
+		state = "revoked"
+	case remaining > 0:
+		state = fmt.Sprintf("%s left", remaining.Truncate(time.Second))
+	default:
+		state = "expired"
+	}
+	return fmt.Sprintf("#%d [%s] -> %s: %s (%s)", sent.Id, util.Int64ToTimeString(msg.SentAt().Unix()), receiver, sentContent(msg), state)
+}
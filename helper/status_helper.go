@@ -0,0 +1,190 @@
+package helper
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"wx-cli/client"
+	"wx-cli/storage"
+	"wx-cli/util"
+)
+
+// sessionState is what the bot events tell about the session.
+type sessionState struct {
+	mu          sync.Mutex
+	startedAt   time.Time
+	confirmedAt time.Time // the login was confirmed on the phone, not resumed
+	onlineAt    time.Time
+	syncCheckAt time.Time
+	syncCheck   client.SyncCheckResponse
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// SessionStatus is the diagnostics of the login session, times are unix seconds.
+type SessionStatus struct {
+	Alive              bool
+	CrashReason        string `json:",omitempty"`
+	StartedAt          int64
+	OnlineAt           int64 `json:",omitempty"`
+	SyncCheckAt        int64 `json:",omitempty"`
+	RetCode            string
+	Selector           string
+	LastError          string `json:",omitempty"`
+	LastErrorAt        int64  `json:",omitempty"`
+	Domain             string
+	HotLoginFile       string
+	HotLoginSaved      bool
+	CredentialsSavedAt int64 `json:",omitempty"` // when the hot login file, with skey and pass_ticket, was written
+	HasSKey            bool
+	HasPassTicket      bool
+	Contacts           int
+	Friends            int
+	Groups             int
+	Mps                int
+	Store              storage.CacheSize
+}
+
+func (h *Helper) loadSession() {
+	h.session = &sessionState{startedAt: time.Now()}
+	h.bot.Events.On(h.observeSession, client.EventLogin, client.EventSyncCheck, client.EventError)
+}
+
+func (h *Helper) observeSession(e client.Event) {
+	s := h.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch e := e.(type) {
+	case *client.LoginEvent:
+		switch e.State {
+		case client.LoginStateConfirmed:
+			s.confirmedAt = time.Now()
+		case client.LoginStateOnline:
+			s.onlineAt = time.Now()
+		}
+	case *client.SyncCheckEvent:
+		s.syncCheckAt = time.Now()
+		s.syncCheck = e.Response
+	case *client.ErrorEvent:
+		s.lastError = e.Err.Error()
+		s.lastErrorAt = time.Now()
+	}
+}
+
+// resumed tells if the session was resumed from the hot login file,
+// so the UserNames saved by the last session are still valid.
+func (s *sessionState) resumed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.confirmedAt.IsZero()
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func (h *Helper) Status() *SessionStatus {
+	s := h.session
+	s.mu.Lock()
+	status := &SessionStatus{
+		Alive:       h.bot.Alive(),
+		StartedAt:   s.startedAt.Unix(),
+		OnlineAt:    unixOrZero(s.onlineAt),
+		SyncCheckAt: unixOrZero(s.syncCheckAt),
+		RetCode:     s.syncCheck.RetCode,
+		Selector:    s.syncCheck.Selector,
+		LastError:   s.lastError,
+		LastErrorAt: unixOrZero(s.lastErrorAt),
+	}
+	s.mu.Unlock()
+
+	if err := h.bot.CrashReason(); err != nil {
+		status.CrashReason = err.Error()
+	}
+	status.Domain = string(h.bot.Caller.Client.Domain)
+	status.HotLoginFile = h.cfg.StorageFileName
+	if stat, err := os.Stat(h.cfg.StorageFileName); err == nil {
+		status.HotLoginSaved = true
+		status.CredentialsSavedAt = stat.ModTime().Unix()
+	}
+	if req := h.bot.Storage.Request; req != nil {
+		status.HasSKey = req.Skey != ""
+	}
+	if info := h.bot.Storage.LoginInfo; info != nil {
+		status.HasPassTicket = info.PassTicket != ""
+	}
+	// count what the session holds, the saved directory may be stale or not loaded yet
+	if h.self != nil {
+		for _, member := range h.self.Contacts() {
+			status.Contacts++
+			switch {
+			case member.IsFriend():
+				status.Friends++
+			case member.IsGroup():
+				status.Groups++
+			case member.IsMP():
+				status.Mps++
+			}
+		}
+	}
+	if h.cache != nil {
+		status.Store = h.cache.Size()
+	}
+	return status
+}
+
+// since formats the time of a unix timestamp and how long ago it was.
+func since(unix int64, now time.Time) string {
+	if unix == 0 {
+		return "never"
+	}
+	ago := now.Sub(time.Unix(unix, 0)).Round(time.Second)
+	return fmt.Sprintf("%s (%s ago)", util.Int64ToTimeString(unix), ago)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func StatusToString(s *SessionStatus) string {
+	now := time.Now()
+	var lines []string
+	add := func(name, format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf("%-12s "+format, append([]interface{}{name + ":"}, args...)...))
+	}
+	if s.Alive {
+		add("Login", "online since %s", since(s.OnlineAt, now))
+	} else if s.CrashReason != "" {
+		add("Login", "offline, crashed: %s", s.CrashReason)
+	} else {
+		add("Login", "offline")
+	}
+	add("Started", "%s", since(s.StartedAt, now))
+	if s.SyncCheckAt == 0 {
+		add("Sync check", "never")
+	} else {
+		add("Sync check", "retcode %s, selector %s at %s", s.RetCode, s.Selector, since(s.SyncCheckAt, now))
+	}
+	if s.LastError != "" {
+		add("Last error", "%s at %s", s.LastError, since(s.LastErrorAt, now))
+	}
+	add("Domain", "%s", s.Domain)
+	if s.HotLoginSaved {
+		add("Hot login", "%s saved %s", s.HotLoginFile, since(s.CredentialsSavedAt, now))
+	} else {
+		add("Hot login", "%s missing", s.HotLoginFile)
+	}
+	add("Credentials", "skey %s, pass_ticket %s", yesNo(s.HasSKey), yesNo(s.HasPassTicket))
+	add("Contacts", "%d (%d friends, %d groups, %d official accounts)", s.Contacts, s.Friends, s.Groups, s.Mps)
+	add("Messages", "%d stored, %d recalls, %d sent, %d friend requests, %d search terms",
+		s.Store.Messages, s.Store.Recalls, s.Store.Sent, s.Store.FriendRequests, s.Store.Terms)
+	return strings.Join(lines, "\n")
+}
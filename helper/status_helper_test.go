@@ -0,0 +1,39 @@
+package helper
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"wx-cli/client"
+)
+
+func TestStatus(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.StorageFileName = filepath.Join(t.TempDir(), "storage.json")
+	h := NewHelper(cfg)
+	status := h.Status()
+	if status.Alive || status.HotLoginSaved || status.SyncCheckAt != 0 {
+		t.Errorf("fresh status = %+v", status)
+	}
+
+	if err := ioutil.WriteFile(cfg.StorageFileName, []byte("{}"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	h.bot.Events.Publish(&client.SyncCheckEvent{Response: client.SyncCheckResponse{RetCode: "0", Selector: "2"}})
+	h.bot.Events.Publish(&client.ErrorEvent{Err: errors.New("connection reset"), Retry: true})
+	status = h.Status()
+	if status.RetCode != "0" || status.Selector != "2" || status.SyncCheckAt == 0 {
+		t.Errorf("sync check not recorded: %+v", status)
+	}
+	if !status.HotLoginSaved || status.CredentialsSavedAt == 0 {
+		t.Errorf("hot login file not found: %+v", status)
+	}
+	text := StatusToString(status)
+	for _, want := range []string{"retcode 0, selector 2", "connection reset", "storage.json saved"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q in\n%s", want, text)
+		}
+	}
+}
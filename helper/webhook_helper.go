@@ -0,0 +1,150 @@
+package helper
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"wx-cli/client"
+	"wx-cli/export"
+	"wx-cli/util"
+	"wx-cli/webhook"
+)
+
+// WebhookConfig posts incoming messages to Hooks, media links point to
+// MediaAddr which serves the media from local storage.
+// Media links are signed with MediaSecret, or the first Secret of Hooks when empty.
+type WebhookConfig struct {
+	Hooks       []*webhook.Config
+	MediaAddr   string // like 127.0.0.1:8765, empty for no media links
+	MediaURL    string // base url of media links, http://<MediaAddr> by default
+	MediaSecret string `json:",omitempty"`
+}
+
+func (c *WebhookConfig) mediaSecret() string {
+	if c.MediaSecret != "" {
+		return c.MediaSecret
+	}
+	for _, hook := range c.Hooks {
+		if hook.Secret != "" {
+			return hook.Secret
+		}
+	}
+	return ""
+}
+
+func categoryName(category client.MessageCategory) string {
+	switch category {
+	case client.CategorySystem:
+		return "system"
+	case client.CategoryFriend:
+		return "friend"
+	case client.CategoryGroup:
+		return "group"
+	case client.CategoryMP:
+		return "mp"
+	}
+	return "unknown"
+}
+
+func (h *Helper) webhookPayload(msg *client.Message) *webhook.Payload {
+	kind := export.Kind(msg)
+	payload := &webhook.Payload{
+		MsgId:      msg.MsgId,
+		Category:   categoryName(msg.Category),
+		Type:       kind,
+		Content:    exportText(msg, kind),
+		At:         msg.IsAt(),
+		IsSelf:     msg.IsSendBySelf(),
+		CreateTime: msg.CreateTime,
+	}
+	// called on the sync path, so names come from local data only
+	if sender, ok := h.localUser(msg.FromUserName); ok {
+		payload.Sender = h.GetName(sender)
+	}
+	if receiver, ok := h.localUser(msg.ToUserName); ok {
+		payload.Receiver = h.GetName(receiver)
+	}
+	if msg.Category == client.CategoryGroup {
+		payload.Group = h.GetName(&client.User{UserName: peer(msg)})
+		if msg.IsSendBySelf() {
+			payload.SenderInGroup = h.GetName(h.self.User)
+		} else if user, ok := h.localGroupMember(msg.FromUserName, msg.SenderInGroupUserName()); ok {
+			payload.SenderInGroup = h.GetName(user)
+		}
+	}
+	if msg.HasFile() && h.cfg.Webhook.MediaAddr != "" {
+		base := h.cfg.Webhook.MediaURL
+		if base == "" {
+			base = "http://" + h.cfg.Webhook.MediaAddr
+		}
+		payload.MediaURL = webhook.MediaURL(base, h.cfg.Webhook.mediaSecret(), msg.MsgId)
+	}
+	return payload
+}
+
+// mediaPath downloads the media of a stored message next to the other local files.
+func (h *Helper) mediaPath(msgId string) (string, error) {
+	msg, ok := h.cache.GetMessage(msgId)
+	if !ok {
+		return "", fmt.Errorf("no such message %s", msgId)
+	}
+	name, err := export.SaveMedia(util.GetCurrentPath(), msg)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(util.GetCurrentPath(), name), nil
+}
+
+func (h *Helper) loadWebhooks(filePath string) error {
+	cfg := h.cfg.Webhook
+	if len(cfg.Hooks) == 0 {
+		return nil
+	}
+	if cfg.MediaAddr != "" && cfg.mediaSecret() == "" {
+		return fmt.Errorf("webhook media server needs a MediaSecret or a webhook Secret to sign links")
+	}
+	forwarder, err := webhook.NewForwarder(cfg.Hooks, filePath+"_webhook_queue.json", filePath+"_webhook_dead.jsonl")
+	if err != nil {
+		return err
+	}
+	h.webhooks = forwarder
+	go forwarder.Run(h.Done())
+	if cfg.MediaAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(cfg.MediaAddr, webhook.MediaHandler(cfg.mediaSecret(), h.mediaPath)); err != nil {
+				fmt.Println("webhook media server err:", err)
+			}
+		}()
+	}
+	return nil
+}
+
+func (h *Helper) postWebhooks(msg *client.Message) {
+	if h.webhooks == nil {
+		return
+	}
+	if err := h.webhooks.Enqueue(h.webhookPayload(msg)); err != nil {
+		fmt.Println("webhook enqueue err:", err)
+	}
+}
+
+func (h *Helper) WebhookStatus() (*webhook.Status, error) {
+	if h.webhooks == nil {
+		return nil, fmt.Errorf("no webhooks configured")
+	}
+	return h.webhooks.Status()
+}
+
+func (h *Helper) WebhookDeadLetters() ([]*webhook.Delivery, error) {
+	if h.webhooks == nil {
+		return nil, fmt.Errorf("no webhooks configured")
+	}
+	return h.webhooks.DeadLetters()
+}
+
+func (h *Helper) RequeueWebhookDeadLetters() (int, error) {
+	if h.webhooks == nil {
+		return 0, fmt.Errorf("no webhooks configured")
+	}
+	return h.webhooks.RequeueDeadLetters()
+}
@@ -0,0 +1,244 @@
+package helper
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+	"wx-cli/client"
+	"wx-cli/util"
+)
+
+// ZombiePending is the status of a friend not checked yet,
+// client.ZombieUnknown is a check that gave no answer and is not repeated.
+const ZombiePending = "Pending"
+
+type ZombieTarget struct {
+	Name      string
+	Status    string
+	CheckedAt int64
+}
+
+// pending also takes the unchecked friends of audits saved before ZombiePending.
+func (t *ZombieTarget) pending() bool {
+	return t.Status == ZombiePending || t.Status == client.ZombieUnknown.String() && t.CheckedAt == 0
+}
+
+// ZombieAudit is saved after every batch so an interrupted audit can be resumed.
+// GroupName is the temporary group, reused when resuming.
+type ZombieAudit struct {
+	Targets   []*ZombieTarget
+	GroupName string `json:",omitempty"`
+	CreatedAt int64
+}
+
+func zombieAuditFileName() string {
+	return fmt.Sprintf("%s/zombie.json", util.GetCurrentPath())
+}
+
+func (a *ZombieAudit) Save() error {
+	b, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(zombieAuditFileName(), b, 0666)
+}
+
+func LoadZombieAudit() (*ZombieAudit, error) {
+	b, err := ioutil.ReadFile(zombieAuditFileName())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("no zombie audit, use `zombie start` first")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var audit ZombieAudit
+	if err = json.Unmarshal(b, &audit); err != nil {
+		return nil, err
+	}
+	return &audit, nil
+}
+
+func (a *ZombieAudit) Count(status string) int {
+	var count int
+	for _, target := range a.Targets {
+		if target.Status == status && !target.pending() {
+			count++
+		}
+	}
+	return count
+}
+
+// Pending counts the friends not checked yet.
+func (a *ZombieAudit) Pending() int {
+	var count int
+	for _, target := range a.Targets {
+		if target.pending() {
+			count++
+		}
+	}
+	return count
+}
+
+// Export writes the audit as csv.
+func (a *ZombieAudit) Export(fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	_ = w.Write([]string{"Name", "Status", "CheckedAt"})
+	for _, target := range a.Targets {
+		var checkedAt string
+		if target.CheckedAt > 0 {
+			checkedAt = util.Int64ToTimeString(target.CheckedAt)
+		}
+		_ = w.Write([]string{target.Name, target.Status, checkedAt})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// NewZombieAudit starts an audit of all friends, replacing the previous one.
+func (h *Helper) NewZombieAudit() (*ZombieAudit, error) {
+	friends, err := h.self.Friends()
+	if err != nil {
+		return nil, err
+	}
+	audit := &ZombieAudit{CreatedAt: time.Now().Unix()}
+	for _, friend := range friends {
+		audit.Targets = append(audit.Targets, &ZombieTarget{
+			Name:   h.GetName(friend.User),
+			Status: ZombiePending,
+		})
+	}
+	return audit, audit.Save()
+}
+
+// zombieChecker reuses the temporary group of audit if it is still there.
+func (h *Helper) zombieChecker(audit *ZombieAudit) *client.ZombieChecker {
+	if audit.GroupName != "" {
+		if user, err := h.findExactContact(audit.GroupName); err == nil && user.IsGroup() {
+			return h.self.NewZombieCheckerInGroup(&client.Group{User: user})
+		}
+	}
+	audit.GroupName = fmt.Sprintf("wx-cli check %s", time.Now().Format("0102 15:04"))
+	return h.self.NewZombieChecker(audit.GroupName)
+}
+
+// RunZombieAudit probes the unchecked friends of audit batch by batch through a temporary group,
+// onResult is called for every friend checked.
+func (h *Helper) RunZombieAudit(ctx context.Context, audit *ZombieAudit, onResult func(target *ZombieTarget)) (*client.Group, error) {
+	friends, err := h.self.Friends()
+	if err != nil {
+		return nil, err
+	}
+	// friends may share a name, the audit has a target for each of them
+	byName := make(map[string]client.Friends, len(friends))
+	for _, friend := range friends {
+		name := h.GetName(friend.User)
+		byName[name] = append(byName[name], friend)
+	}
+	var pending client.Friends
+	targets := make(map[string]*ZombieTarget)
+	for _, target := range audit.Targets {
+		same := byName[target.Name]
+		if len(same) == 0 {
+			continue
+		}
+		byName[target.Name] = same[1:]
+		if target.pending() {
+			pending = append(pending, same[0])
+			targets[same[0].UserName] = target
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	batchSize := h.cfg.Zombie.BatchSize
+	if batchSize < 2 {
+		batchSize = 2
+	}
+	checker := h.zombieChecker(audit)
+	defer func() {
+		if group := checker.Group(); group != nil {
+			h.directory.Apply([]*client.User{group.User}, nil)
+			h.saveDirectory()
+		}
+	}()
+	for start := 0; start < len(pending); start += batchSize {
+		if start > 0 {
+			select {
+			case <-ctx.Done():
+				return checker.Group(), ctx.Err()
+			case <-time.After(time.Duration(h.cfg.Zombie.Interval)):
+			}
+		}
+		end := start + batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+		probe := batch
+		if checker.Group() == nil && len(batch) == 1 {
+			// creating the group needs 2 friends, add a checked one whose result is ignored
+			extra := h.zombieCompanion(friends, targets)
+			if extra == nil {
+				return nil, errors.New("a group must be at least 2 members")
+			}
+			probe = client.Friends{batch[0], extra}
+		}
+		result, err := checker.Check(probe...)
+		if err != nil {
+			return checker.Group(), err
+		}
+		for _, friend := range batch {
+			target := targets[friend.UserName]
+			target.Status = result[friend.UserName].String()
+			target.CheckedAt = time.Now().Unix()
+			if onResult != nil {
+				onResult(target)
+			}
+		}
+		if err = audit.Save(); err != nil {
+			return checker.Group(), err
+		}
+	}
+	return checker.Group(), nil
+}
+
+// zombieCompanion picks a friend that is not pending to create the temporary group with.
+func (h *Helper) zombieCompanion(friends client.Friends, pending map[string]*ZombieTarget) *client.Friend {
+	for _, friend := range friends {
+		if _, ok := pending[friend.UserName]; !ok {
+			return friend
+		}
+	}
+	return nil
+}
+
+func ZombieReport(audit *ZombieAudit, all bool) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Zombie audit %s: %d normal, %d deleted, %d blocked, %d unknown, %d unchecked\n",
+		util.Int64ToTimeString(audit.CreatedAt),
+		audit.Count(client.ZombieNormal.String()), audit.Count(client.ZombieDeleted.String()),
+		audit.Count(client.ZombieBlocked.String()), audit.Count(client.ZombieUnknown.String()), audit.Pending()))
+	for _, target := range audit.Targets {
+		if !all && target.Status == client.ZombieNormal.String() {
+			continue
+		}
+		builder.WriteString(ZombieTargetToString(target))
+		builder.WriteString("\n")
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
+
+func ZombieTargetToString(target *ZombieTarget) string {
+	return fmt.Sprintf("[%s] %s", target.Status, target.Name)
+}
@@ -0,0 +1,42 @@
+package helper
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestZombieAuditExport(t *testing.T) {
+	audit := &ZombieAudit{Targets: []*ZombieTarget{
+		{Name: "alice", Status: "Normal", CheckedAt: 1600000000},
+		{Name: "bob", Status: "Deleted", CheckedAt: 1600000000},
+		{Name: "carol", Status: "Unknown"},
+	}}
+	if n := audit.Count("Deleted"); n != 1 {
+		t.Errorf("Count(Deleted) = %d, want 1", n)
+	}
+	// carol was saved before the pending status, an unknown result is not checked again
+	audit.Targets = append(audit.Targets, &ZombieTarget{Name: "dave", Status: "Unknown", CheckedAt: 1600000000})
+	if pending, unknown := audit.Pending(), audit.Count("Unknown"); pending != 1 || unknown != 1 {
+		t.Errorf("Pending = %d, Count(Unknown) = %d, want 1 and 1", pending, unknown)
+	}
+	audit.Targets = audit.Targets[:3]
+	report := ZombieReport(audit, false)
+	if strings.Contains(report, "alice") || !strings.Contains(report, "[Deleted] bob") {
+		t.Errorf("report should list only abnormal friends:\n%s", report)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "zombie.csv")
+	if err := audit.Export(fileName); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 4 || lines[0] != "Name,Status,CheckedAt" || !strings.HasPrefix(lines[2], "bob,Deleted,") || lines[3] != "carol,Unknown," {
+		t.Errorf("unexpected csv:\n%s", b)
+	}
+}
@@ -1,16 +1,19 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"github.com/peterh/liner"
 	"github.com/skip2/go-qrcode"
 	"github.com/urfave/cli/v2"
+	"io"
 	"log"
 	"os"
 	"strings"
 	"wx-cli/client"
 	"wx-cli/cmd"
 	"wx-cli/helper"
+	"wx-cli/util"
 )
 
 func ConsoleQrCode(uuid string) {
@@ -33,44 +36,87 @@ func MessageHandler(msg *client.Message) {
 	h.StoreMessage(msg)
 }
 
-func SyncCheckCallback(resp client.SyncCheckResponse) {
-	if !resp.Success() {
+func SyncCheckCallback(e client.Event) {
+	if resp := e.(*client.SyncCheckEvent).Response; !resp.Success() {
 		fmt.Println(resp.Error())
 	}
 }
 
 var app *cli.App
 var h *helper.Helper
+var cfg *helper.Config
 
 func mainLoop() {
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetTabCompletionStyle(liner.TabPrints)
+	line.SetWordCompleter(cmd.Complete)
+	cmd.Prompt = line.Prompt
+	if f, err := os.Open(cfg.HistoryFileName); err == nil {
+		_, _ = line.ReadHistory(f)
+		_ = f.Close()
+	}
+	defer saveHistory(line)
+
 	for {
 		select {
 		case <-h.Done():
+			return
 		default:
-			fmt.Print("> ")
-			command, err := bufio.NewReader(os.Stdin).ReadString(';')
+			command, err := line.Prompt("> ")
+			if errors.Is(err, liner.ErrPromptAborted) {
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				return
+			}
 			if err != nil {
 				panic(err)
 			}
-			command = fmt.Sprintf("# %s", strings.TrimRight(command, ";"))
+			command = strings.TrimSpace(strings.TrimRight(command, ";"))
+			if command == "" {
+				continue
+			}
+			line.AppendHistory(command)
 			execute(command)
 		}
 	}
 }
 
+func saveHistory(line *liner.State) {
+	f, err := os.Create(cfg.HistoryFileName)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer f.Close()
+	if _, err = line.WriteHistory(f); err != nil {
+		fmt.Println(err)
+	}
+}
+
 func execute(command string) {
-	args := strings.Split(command, " ")
+	args, err := util.SplitArgs(command)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	args = append([]string{"#"}, args...)
 	if err := app.Run(args); err != nil {
 		fmt.Println(err.Error())
 	}
 }
 
 func main() {
-	cfg := &helper.Config{
-		StorageFileName: "storage.json",
+	var err error
+	cfg, err = helper.LoadConfig("config.json")
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
 	h = helper.NewHelper(cfg)
-	h.BindSyncCheckCallback(SyncCheckCallback)
+	h.Subscribe(SyncCheckCallback, client.SubscribeOption{Types: []client.EventType{client.EventSyncCheck}})
 	h.BindUUIDCallback(ConsoleQrCode)
 	h.BindScanCallBack(ScanCallback)
 	h.BindLoginCallBack(LoginCallback)
@@ -80,6 +126,7 @@ func main() {
 		fmt.Println(err)
 		return
 	}
+	defer h.Close()
 
 	app = &cli.App{
 		Name:            "wx-cli",
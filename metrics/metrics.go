@@ -0,0 +1,287 @@
+// Package metrics keeps counters and histograms in memory and writes them
+// in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// DefaultBuckets are the histogram upper bounds in seconds for request latencies.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Label is one name="value" pair of a sample.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one labelled value of a family. Histogram samples carry
+// the cumulative bucket counts, Count and Sum instead of Value.
+type Sample struct {
+	Labels  []Label
+	Value   float64
+	Buckets []uint64
+	Count   uint64
+	Sum     float64
+}
+
+// Family is a metric with all its samples.
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Bounds  []float64 // histogram bucket upper bounds
+	Samples []Sample
+}
+
+type collector interface {
+	collect() Family
+}
+
+// Registry holds the metrics of the process.
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[name]; ok {
+		panic(fmt.Sprintf("metric %s registered twice", name))
+	}
+	r.collectors[name] = c
+}
+
+// vec keeps one value per combination of label values.
+type vec struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	labels []string
+	keys   []string
+	values map[string][]string
+}
+
+func newVec(name, help string, labels []string) vec {
+	return vec{name: name, help: help, labels: labels, values: make(map[string][]string)}
+}
+
+// key returns the map key of values, adding it when new. Called with mu held.
+func (v *vec) key(values []string) string {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s wants %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	if _, ok := v.values[key]; !ok {
+		v.values[key] = append([]string(nil), values...)
+		v.keys = append(v.keys, key)
+		sort.Strings(v.keys)
+	}
+	return key
+}
+
+func (v *vec) labelsOf(key string) []Label {
+	labels := make([]Label, len(v.labels))
+	for i, name := range v.labels {
+		labels[i] = Label{Name: name, Value: v.values[key][i]}
+	}
+	return labels
+}
+
+// Counter is a value that only goes up, partitioned by labels.
+type Counter struct {
+	vec
+	counts map[string]float64
+}
+
+// Counter registers a counter with the label names.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{vec: newVec(name, help, labels), counts: make(map[string]float64)}
+	r.register(name, c)
+	return c
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[c.key(labelValues)] += delta
+}
+
+func (c *Counter) collect() Family {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f := Family{Name: c.name, Help: c.help, Type: TypeCounter}
+	for _, key := range c.keys {
+		f.Samples = append(f.Samples, Sample{Labels: c.labelsOf(key), Value: c.counts[key]})
+	}
+	return f
+}
+
+// Histogram counts observations into buckets, partitioned by labels.
+type Histogram struct {
+	vec
+	bounds  []float64
+	buckets map[string][]uint64
+	counts  map[string]uint64
+	sums    map[string]float64
+}
+
+// Histogram registers a histogram, nil bounds use DefaultBuckets.
+func (r *Registry) Histogram(name, help string, bounds []float64, labels ...string) *Histogram {
+	if bounds == nil {
+		bounds = DefaultBuckets
+	}
+	h := &Histogram{
+		vec:     newVec(name, help, labels),
+		bounds:  bounds,
+		buckets: make(map[string][]uint64),
+		counts:  make(map[string]uint64),
+		sums:    make(map[string]float64),
+	}
+	r.register(name, h)
+	return h
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := h.key(labelValues)
+	buckets, ok := h.buckets[key]
+	if !ok {
+		buckets = make([]uint64, len(h.bounds))
+		h.buckets[key] = buckets
+	}
+	for i, bound := range h.bounds {
+		if value <= bound {
+			buckets[i]++
+		}
+	}
+	h.counts[key]++
+	h.sums[key] += value
+}
+
+func (h *Histogram) collect() Family {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f := Family{Name: h.name, Help: h.help, Type: TypeHistogram, Bounds: h.bounds}
+	for _, key := range h.keys {
+		f.Samples = append(f.Samples, Sample{
+			Labels:  h.labelsOf(key),
+			Buckets: append([]uint64(nil), h.buckets[key]...),
+			Count:   h.counts[key],
+			Sum:     h.sums[key],
+		})
+	}
+	return f
+}
+
+type funcCollector struct {
+	family Family
+	f      func() []Sample
+}
+
+func (c *funcCollector) collect() Family {
+	f := c.family
+	f.Samples = c.f()
+	return f
+}
+
+// Func registers a counter or gauge whose samples are read from f on every collection,
+// for values already counted elsewhere.
+func (r *Registry) Func(name, help, typ string, f func() []Sample) {
+	r.register(name, &funcCollector{family: Family{Name: name, Help: help, Type: typ}, f: f})
+}
+
+// Families collects every metric sorted by name.
+func (r *Registry) Families() []Family {
+	r.mu.Lock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.Unlock()
+	families := make([]Family, len(collectors))
+	for i, c := range collectors {
+		families[i] = c.collect()
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(labels []Label, extra ...Label) string {
+	labels = append(append([]Label(nil), labels...), extra...)
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = fmt.Sprintf(`%s="%s"`, l.Name, labelEscaper.Replace(l.Value))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// Write writes the families in the Prometheus text format.
+func Write(w io.Writer, families []Family) error {
+	var b strings.Builder
+	for _, f := range families {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.Name, f.Help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.Name, f.Type)
+		for _, s := range f.Samples {
+			if f.Type != TypeHistogram {
+				fmt.Fprintf(&b, "%s%s %s\n", f.Name, formatLabels(s.Labels), formatFloat(s.Value))
+				continue
+			}
+			for i, bound := range f.Bounds {
+				le := Label{Name: "le", Value: formatFloat(bound)}
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", f.Name, formatLabels(s.Labels, le), s.Buckets[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", f.Name, formatLabels(s.Labels, Label{Name: "le", Value: "+Inf"}), s.Count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", f.Name, formatLabels(s.Labels), formatFloat(s.Sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", f.Name, formatLabels(s.Labels), s.Count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Handler serves the registry at any path, mount it at /metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = Write(w, r.Families())
+	})
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	requests := r.Counter("wx_requests_total", "Requests.", "endpoint", "code")
+	requests.Inc("synccheck", "200")
+	requests.Inc("synccheck", "200")
+	requests.Add(3, "webwxsync", `5"0`)
+	latency := r.Histogram("wx_request_duration_seconds", "Latency.", []float64{0.1, 1}, "endpoint")
+	latency.Observe(0.05, "synccheck")
+	latency.Observe(0.5, "synccheck")
+	r.Func("wx_cache_size", "Size.", TypeGauge, func() []Sample {
+		return []Sample{{Value: 7}}
+	})
+
+	server := httptest.NewServer(r.Handler())
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	want := `# HELP wx_cache_size Size.
+# TYPE wx_cache_size gauge
+wx_cache_size 7
+# HELP wx_request_duration_seconds Latency.
+# TYPE wx_request_duration_seconds histogram
+wx_request_duration_seconds_bucket{endpoint="synccheck",le="0.1"} 1
+wx_request_duration_seconds_bucket{endpoint="synccheck",le="1"} 2
+wx_request_duration_seconds_bucket{endpoint="synccheck",le="+Inf"} 2
+wx_request_duration_seconds_sum{endpoint="synccheck"} 0.55
+wx_request_duration_seconds_count{endpoint="synccheck"} 2
+# HELP wx_requests_total Requests.
+# TYPE wx_requests_total counter
+wx_requests_total{endpoint="synccheck",code="200"} 2
+wx_requests_total{endpoint="webwxsync",code="5\"0"} 3
+`
+	if got := string(b); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("Content-Type = %s", resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want a panic on missing label values")
+		}
+	}()
+	NewRegistry().Counter("c", "C.", "a", "b").Inc("x")
+}
@@ -0,0 +1,115 @@
+package plugins
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Manager runs the configured plugins and publishes messages to them.
+type Manager struct {
+	mu      sync.RWMutex
+	plugins []*Plugin
+}
+
+// NewManager prepares the plugins of configs, stderr of each plugin goes to
+// <logDir>/<name>.log.
+func NewManager(configs []Config, api API, logDir string) (*Manager, error) {
+	m := &Manager{}
+	names := make(map[string]bool)
+	for _, cfg := range configs {
+		if cfg.Name == "" || len(cfg.Command) == 0 {
+			return nil, fmt.Errorf("plugin %q: name and command required", cfg.Name)
+		}
+		if names[cfg.Name] {
+			return nil, fmt.Errorf("duplicate plugin %s", cfg.Name)
+		}
+		names[cfg.Name] = true
+		m.plugins = append(m.plugins, newPlugin(cfg, api, logDir))
+	}
+	return m, nil
+}
+
+func (m *Manager) get(name string) (*Plugin, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range m.plugins {
+		if p.cfg.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no such plugin %s", name)
+}
+
+// StartAll starts the plugins not disabled.
+func (m *Manager) StartAll() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range m.plugins {
+		if !p.cfg.Disabled {
+			p.Start()
+		}
+	}
+}
+
+func (m *Manager) StopAll() {
+	m.mu.RLock()
+	plugins := make([]*Plugin, len(m.plugins))
+	copy(plugins, m.plugins)
+	m.mu.RUnlock()
+	var wg sync.WaitGroup
+	for _, p := range plugins {
+		wg.Add(1)
+		go func(p *Plugin) {
+			defer wg.Done()
+			p.Stop()
+		}(p)
+	}
+	wg.Wait()
+}
+
+func (m *Manager) Start(name string) error {
+	p, err := m.get(name)
+	if err != nil {
+		return err
+	}
+	p.Start()
+	return nil
+}
+
+func (m *Manager) Stop(name string) error {
+	p, err := m.get(name)
+	if err != nil {
+		return err
+	}
+	p.Stop()
+	return nil
+}
+
+func (m *Manager) Restart(name string) error {
+	p, err := m.get(name)
+	if err != nil {
+		return err
+	}
+	p.Stop()
+	p.Start()
+	return nil
+}
+
+// Publish sends event to every running plugin subscribed to it, it never blocks.
+func (m *Manager) Publish(event *MessageEvent) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range m.plugins {
+		p.publish(event)
+	}
+}
+
+func (m *Manager) Status() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status := make([]Status, len(m.plugins))
+	for i, p := range m.plugins {
+		status[i] = p.Status()
+	}
+	return status
+}
@@ -0,0 +1,417 @@
+// Package plugins runs external processes that handle messages and call back
+// a limited API, speaking JSON-RPC 2.0 over their stdin and stdout.
+//
+// The host calls "initialize" with the plugin config, the plugin answers with its
+// subscription. Subscribed messages are sent as "message" notifications and
+// "shutdown" is called before the process is stopped. Plugins may call "send",
+// "reply", "contacts.find", "contacts.search" and "status" if permitted, and "log".
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	StateStopped  = "stopped"
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateCrashed  = "crashed" // waiting to be restarted
+	StateFailed   = "failed"  // crashed too often, not restarted
+)
+
+const (
+	eventBufferSize = 64
+	shutdownTimeout = 3 * time.Second
+	maxRestarts     = 5
+	restartWindow   = time.Minute
+	maxBackoff      = 30 * time.Second
+)
+
+// Permissions grant the API methods to plugins.
+const (
+	PermissionSend     = "send"     // send
+	PermissionReply    = "reply"    // reply
+	PermissionContacts = "contacts" // contacts.find, contacts.search
+	PermissionStatus   = "status"   // status
+)
+
+// Config of a plugin, Config is passed to the plugin as is.
+type Config struct {
+	Name        string
+	Command     []string
+	Dir         string          `json:",omitempty"`
+	Env         []string        `json:",omitempty"` // extra environment variables like KEY=value
+	Config      json.RawMessage `json:",omitempty"`
+	Permissions []string        `json:",omitempty"`
+	Disabled    bool            `json:",omitempty"`
+}
+
+// Contact is a friend or group as seen by plugins.
+type Contact struct {
+	UserName   string
+	Name       string
+	NickName   string
+	RemarkName string
+	IsGroup    bool
+}
+
+// MessageEvent is the params of the "message" notification.
+type MessageEvent struct {
+	MsgId      string
+	Type       string
+	Sender     string
+	Group      string `json:",omitempty"`
+	Text       string
+	At         bool
+	IsSelf     bool
+	CreateTime int64
+}
+
+// Subscription is the result of "initialize", Types filters messages as in export.Kinds.
+type Subscription struct {
+	Types []string
+	Self  bool // also receive my own messages
+}
+
+func (s *Subscription) match(event *MessageEvent) bool {
+	if event.IsSelf && !s.Self {
+		return false
+	}
+	if len(s.Types) == 0 {
+		return true
+	}
+	for _, t := range s.Types {
+		if t == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// API is what plugins can do, the host implements it.
+type API interface {
+	Send(to, text string) error // to is the exact name of a friend or group
+	Reply(msgId, text string) error
+	FindContact(name string) (*Contact, error)
+	SearchContacts(keyword string, limit int) ([]*Contact, error)
+	// SessionStatus returns the login session diagnostics, any value encodable as json.
+	SessionStatus() (interface{}, error)
+}
+
+// Status of a plugin.
+type Status struct {
+	Name      string
+	State     string
+	Pid       int
+	Restarts  int
+	Dropped   int
+	LastError string
+}
+
+// Plugin supervises one plugin process, restarting it when it crashes.
+type Plugin struct {
+	mu       sync.Mutex
+	cfg      Config
+	api      API
+	logDir   string
+	state    string
+	pid      int
+	sub      Subscription
+	crashes  []time.Time
+	restarts int
+	dropped  int
+	lastErr  error
+	events   chan *MessageEvent
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func newPlugin(cfg Config, api API, logDir string) *Plugin {
+	return &Plugin{cfg: cfg, api: api, logDir: logDir, state: StateStopped}
+}
+
+func (p *Plugin) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := Status{Name: p.cfg.Name, State: p.state, Pid: p.pid, Restarts: p.restarts, Dropped: p.dropped}
+	if p.lastErr != nil {
+		status.LastError = p.lastErr.Error()
+	}
+	return status
+}
+
+func (p *Plugin) setState(state string, pid int) {
+	p.mu.Lock()
+	p.state = state
+	p.pid = pid
+	p.mu.Unlock()
+}
+
+// Start runs the plugin under supervision, it does nothing if already running.
+func (p *Plugin) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		return
+	}
+	p.state = StateStarting
+	p.crashes = nil
+	p.events = make(chan *MessageEvent, eventBufferSize)
+	p.stop = make(chan struct{})
+	p.stopped = make(chan struct{})
+	go p.supervise(p.events, p.stop, p.stopped)
+}
+
+// Stop shuts the plugin down and waits for the process to exit.
+func (p *Plugin) Stop() {
+	p.mu.Lock()
+	stop, stopped := p.stop, p.stopped
+	p.stop = nil
+	p.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-stopped
+}
+
+// publish queues event without blocking, events are dropped when the plugin
+// is not running or falls behind.
+func (p *Plugin) publish(event *MessageEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != StateRunning || !p.sub.match(event) {
+		return
+	}
+	select {
+	case p.events <- event:
+	default:
+		p.dropped++
+	}
+}
+
+// supervise restarts the process until stop is closed, the channels are those
+// of one Start and passed in since a later Start replaces the fields.
+func (p *Plugin) supervise(events chan *MessageEvent, stop, stopped chan struct{}) {
+	defer close(stopped)
+	backoff := time.Second
+	for {
+		err := p.runOnce(events, stop)
+		select {
+		case <-stop:
+			p.setState(StateStopped, 0)
+			return
+		default:
+		}
+		now := time.Now()
+		p.mu.Lock()
+		p.lastErr = err
+		p.restarts++
+		var recent []time.Time
+		for _, t := range p.crashes {
+			if now.Sub(t) < restartWindow {
+				recent = append(recent, t)
+			}
+		}
+		p.crashes = append(recent, now)
+		failed := len(p.crashes) > maxRestarts
+		if failed {
+			p.state = StateFailed
+			p.stop = nil
+		} else {
+			p.state = StateCrashed
+		}
+		p.pid = 0
+		p.mu.Unlock()
+		if failed {
+			return
+		}
+		select {
+		case <-stop:
+			p.setState(StateStopped, 0)
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (p *Plugin) openLog() (io.WriteCloser, error) {
+	if p.logDir == "" {
+		return nopWriteCloser{io.Discard}, nil
+	}
+	if err := os.MkdirAll(p.logDir, 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filepath.Join(p.logDir, p.cfg.Name+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// runOnce runs the process until it exits or stop is closed, sending it the queued events.
+func (p *Plugin) runOnce(events chan *MessageEvent, stop chan struct{}) error {
+	if len(p.cfg.Command) == 0 {
+		return errors.New("no command")
+	}
+	logFile, err := p.openLog()
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	cmd := exec.Command(p.cfg.Command[0], p.cfg.Command[1:]...)
+	cmd.Dir = p.cfg.Dir
+	cmd.Env = append(os.Environ(), p.cfg.Env...)
+	cmd.Stderr = logFile
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	p.setState(StateStarting, cmd.Process.Pid)
+	c := newConn(stdout, stdin, p.handle)
+
+	exited := make(chan error, 1)
+	go func() {
+		<-c.Done()
+		exited <- cmd.Wait()
+	}()
+
+	var sub Subscription
+	params := struct {
+		Name   string
+		Config json.RawMessage `json:",omitempty"`
+	}{p.cfg.Name, p.cfg.Config}
+	if err = c.Call("initialize", params, &sub); err != nil {
+		_ = cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("initialize: %w", err)
+	}
+	p.mu.Lock()
+	p.sub = sub
+	p.state = StateRunning
+	p.mu.Unlock()
+
+	for {
+		select {
+		case event := <-events:
+			if err = c.Notify("message", event); err != nil {
+				_ = cmd.Process.Kill()
+				<-exited
+				return err
+			}
+		case err = <-exited:
+			if err == nil {
+				err = errors.New("exited")
+			}
+			return err
+		case <-stop:
+			_ = c.Call("shutdown", nil, nil)
+			_ = stdin.Close()
+			select {
+			case <-exited:
+			case <-time.After(shutdownTimeout):
+				_ = cmd.Process.Kill()
+				<-exited
+			}
+			return nil
+		}
+	}
+}
+
+func (p *Plugin) allowed(permission string) bool {
+	for _, granted := range p.cfg.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func forbidden(method string) error {
+	return &RPCError{Code: codeForbidden, Message: method + " not permitted"}
+}
+
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &RPCError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// handle serves the API calls of the plugin.
+func (p *Plugin) handle(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "log":
+		var args struct{ Message string }
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		fmt.Printf("[%s] %s\n", p.cfg.Name, args.Message)
+		return true, nil
+	case "send":
+		if !p.allowed(PermissionSend) {
+			return nil, forbidden(method)
+		}
+		var args struct{ To, Text string }
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		return true, p.api.Send(args.To, args.Text)
+	case "reply":
+		if !p.allowed(PermissionReply) {
+			return nil, forbidden(method)
+		}
+		var args struct{ MsgId, Text string }
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		return true, p.api.Reply(args.MsgId, args.Text)
+	case "contacts.find":
+		if !p.allowed(PermissionContacts) {
+			return nil, forbidden(method)
+		}
+		var args struct{ Name string }
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		return p.api.FindContact(args.Name)
+	case "contacts.search":
+		if !p.allowed(PermissionContacts) {
+			return nil, forbidden(method)
+		}
+		var args struct {
+			Keyword string
+			Limit   int
+		}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		return p.api.SearchContacts(args.Keyword, args.Limit)
+	case "status":
+		if !p.allowed(PermissionStatus) {
+			return nil, forbidden(method)
+		}
+		return p.api.SessionStatus()
+	}
+	return nil, &RPCError{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
@@ -0,0 +1,114 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestHelperPlugin is the plugin process run by the tests, it echoes text messages.
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv("WX_TEST_PLUGIN") != "1" {
+		return
+	}
+	var c *conn
+	c = newConn(os.Stdin, os.Stdout, func(method string, params json.RawMessage) (interface{}, error) {
+		switch method {
+		case "initialize":
+			return Subscription{Types: []string{"text"}}, nil
+		case "message":
+			var event MessageEvent
+			_ = json.Unmarshal(params, &event)
+			if event.Text == "crash" {
+				os.Exit(3)
+			}
+			_ = c.Call("reply", map[string]string{"MsgId": event.MsgId, "Text": "echo " + event.Text}, nil)
+			var rpcErr *RPCError
+			if err := c.Call("send", map[string]string{"To": "x", "Text": "y"}, nil); errors.As(err, &rpcErr) && rpcErr.Code == codeForbidden {
+				_ = c.Call("reply", map[string]string{"MsgId": event.MsgId, "Text": "send forbidden"}, nil)
+			}
+			return nil, nil
+		case "shutdown":
+			return true, nil
+		}
+		return nil, &RPCError{Code: codeMethodNotFound, Message: method}
+	})
+	<-c.Done()
+	os.Exit(0)
+}
+
+type fakeAPI struct {
+	replies chan string
+}
+
+func (a *fakeAPI) Send(to, text string) error { return nil }
+func (a *fakeAPI) Reply(msgId, text string) error {
+	a.replies <- msgId + ":" + text
+	return nil
+}
+func (a *fakeAPI) FindContact(name string) (*Contact, error) { return &Contact{Name: name}, nil }
+func (a *fakeAPI) SearchContacts(keyword string, limit int) ([]*Contact, error) {
+	return nil, nil
+}
+func (a *fakeAPI) SessionStatus() (interface{}, error) { return map[string]bool{"Alive": true}, nil }
+
+func waitState(t *testing.T, m *Manager, state string) Status {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if status := m.Status()[0]; status.State == state {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("plugin not %s: %+v", state, m.Status()[0])
+	return Status{}
+}
+
+func expectReply(t *testing.T, api *fakeAPI, want string) {
+	t.Helper()
+	select {
+	case got := <-api.replies:
+		if got != want {
+			t.Errorf("reply = %q, want %q", got, want)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("no reply, want %q", want)
+	}
+}
+
+func TestManager(t *testing.T) {
+	api := &fakeAPI{replies: make(chan string, 10)}
+	m, err := NewManager([]Config{{
+		Name:        "echo",
+		Command:     []string{os.Args[0], "-test.run=TestHelperPlugin"},
+		Env:         []string{"WX_TEST_PLUGIN=1"},
+		Permissions: []string{PermissionReply},
+	}}, api, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.StartAll()
+	defer m.StopAll()
+	waitState(t, m, StateRunning)
+
+	m.Publish(&MessageEvent{MsgId: "1", Type: "image"})
+	m.Publish(&MessageEvent{MsgId: "2", Type: "text", Text: "hi"})
+	expectReply(t, api, "2:echo hi")
+	expectReply(t, api, "2:send forbidden")
+
+	m.Publish(&MessageEvent{MsgId: "3", Type: "text", Text: "crash"})
+	waitState(t, m, StateCrashed)
+	status := waitState(t, m, StateRunning)
+	if status.Restarts != 1 {
+		t.Errorf("Restarts = %d, want 1", status.Restarts)
+	}
+	m.Publish(&MessageEvent{MsgId: "4", Type: "text", Text: "again"})
+	expectReply(t, api, "4:echo again")
+	expectReply(t, api, "4:send forbidden")
+
+	m.StopAll()
+	waitState(t, m, StateStopped)
+}
@@ -0,0 +1,187 @@
+package plugins
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	jsonrpcVersion = "2.0"
+	callTimeout    = 10 * time.Second
+	maxLineSize    = 4 << 20
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+	codeForbidden      = -32000
+)
+
+// RPCError is the error object of a JSON-RPC response.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// message is a request, notification or response, one per line.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	Id      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *RPCError        `json:"error,omitempty"`
+}
+
+// handlerFunc serves a request of the peer, the result is encoded as json.
+type handlerFunc func(method string, params json.RawMessage) (interface{}, error)
+
+// conn is a JSON-RPC connection of newline delimited messages.
+type conn struct {
+	mu      sync.Mutex // guards w and pending
+	w       io.Writer
+	pending map[string]chan *message
+	lastId  int64
+	handler handlerFunc
+	done    chan struct{}
+	err     error
+}
+
+func newConn(r io.Reader, w io.Writer, handler handlerFunc) *conn {
+	c := &conn{
+		w:       w,
+		pending: make(map[string]chan *message),
+		handler: handler,
+		done:    make(chan struct{}),
+	}
+	go c.read(r)
+	return c
+}
+
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = jsonrpcVersion
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.w.Write(append(b, '\n'))
+	return err
+}
+
+func (c *conn) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			_ = c.write(&message{Id: nil, Error: &RPCError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+		if msg.Method == "" {
+			c.resolve(&msg)
+			continue
+		}
+		go c.serve(&msg)
+	}
+	c.err = scanner.Err()
+	if c.err == nil {
+		c.err = io.EOF
+	}
+	close(c.done)
+}
+
+func (c *conn) resolve(msg *message) {
+	if msg.Id == nil {
+		return
+	}
+	c.mu.Lock()
+	ch, ok := c.pending[string(*msg.Id)]
+	delete(c.pending, string(*msg.Id))
+	c.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+func (c *conn) serve(msg *message) {
+	result, err := c.handler(msg.Method, msg.Params)
+	// notifications get no response
+	if msg.Id == nil {
+		return
+	}
+	resp := &message{Id: msg.Id}
+	if err != nil {
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &RPCError{Code: codeInternalError, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+	} else if resp.Result, err = json.Marshal(result); err != nil {
+		resp.Error = &RPCError{Code: codeInternalError, Message: err.Error()}
+	}
+	_ = c.write(resp)
+}
+
+// Call sends a request and decodes the result into result.
+func (c *conn) Call(method string, params, result interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.lastId++
+	id := json.RawMessage(fmt.Sprint(c.lastId))
+	ch := make(chan *message, 1)
+	c.pending[string(id)] = ch
+	c.mu.Unlock()
+	if err = c.write(&message{Id: &id, Method: method, Params: b}); err != nil {
+		return err
+	}
+	timer := time.NewTimer(callTimeout)
+	defer timer.Stop()
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-c.done:
+		return c.err
+	case <-timer.C:
+		c.mu.Lock()
+		delete(c.pending, string(id))
+		c.mu.Unlock()
+		return fmt.Errorf("%s timed out", method)
+	}
+}
+
+// Notify sends a notification, which has no response.
+func (c *conn) Notify(method string, params interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{Method: method, Params: b})
+}
+
+// Done is closed when the peer closes its output.
+func (c *conn) Done() <-chan struct{} {
+	return c.done
+}
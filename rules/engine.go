@@ -0,0 +1,352 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"wx-cli/client"
+)
+
+const (
+	sampleKey      = "rules.sample"
+	commandTimeout = 30 * time.Second
+	watchInterval  = 2 * time.Second
+)
+
+// Env carries out actions for the engine.
+type Env interface {
+	// Sample describes msg for matching, nil to skip msg.
+	Sample(msg *client.Message) *Sample
+	Reply(msg *client.Message, text string) error
+	ReplyFile(msg *client.Message, filePath string) error
+	Forward(msg *client.Message, targets []string) error
+	Tag(msg *client.Message, tag string) error
+}
+
+type file struct {
+	Rules []*Rule
+}
+
+// Engine dispatches messages to the rules of a json file,
+// the file is reloaded when it changes.
+type Engine struct {
+	mu         sync.RWMutex
+	fileName   string
+	env        Env
+	rules      []*Rule
+	dispatcher *client.MessageMatchDispatcher
+	metrics    *client.DispatchMetrics
+	modTime    time.Time
+}
+
+// NewEngine loads the rules of fileName, a missing file starts with no rules.
+func NewEngine(fileName string, env Env) (*Engine, error) {
+	e := &Engine{fileName: fileName, env: env, metrics: client.NewDispatchMetrics()}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Engine) Reload() error {
+	var rules []*Rule
+	var modTime time.Time
+	stat, err := os.Stat(e.fileName)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		b, err := ioutil.ReadFile(e.fileName)
+		if err != nil {
+			return err
+		}
+		var f file
+		if err = json.Unmarshal(b, &f); err != nil {
+			return fmt.Errorf("%s: %w", e.fileName, err)
+		}
+		names := make(map[string]bool)
+		for _, rule := range f.Rules {
+			if err = rule.compile(); err != nil {
+				return err
+			}
+			if names[rule.Name] {
+				return fmt.Errorf("duplicate rule %s", rule.Name)
+			}
+			names[rule.Name] = true
+		}
+		rules = f.Rules
+		modTime = stat.ModTime()
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = rules
+	e.modTime = modTime
+	e.setDispatcher(e.newDispatcher(rules))
+	return nil
+}
+
+// setDispatcher replaces the dispatcher with the lock held,
+// the old one finishes its queued messages in the background.
+func (e *Engine) setDispatcher(dispatcher *client.MessageMatchDispatcher) {
+	if old := e.dispatcher; old != nil {
+		go old.Close()
+	}
+	e.dispatcher = dispatcher
+}
+
+func (e *Engine) newDispatcher(rules []*Rule) *client.MessageMatchDispatcher {
+	dispatcher := client.NewMessageMatchDispatcher()
+	// actions may send messages or run commands, do not block receiving
+	dispatcher.SetAsync(true)
+	dispatcher.Use(client.RecoverMiddleware(func(ctx *client.MessageContext, r interface{}) {
+		fmt.Printf("rule %s panic: %v\n", ctx.HandlerName(), r)
+	}), e.metrics.Middleware())
+	for _, rule := range rules {
+		if rule.Disabled {
+			continue
+		}
+		rule := rule
+		dispatcher.RegisterNamedHandler(rule.Name, func(msg *client.Message) bool {
+			sample := e.sample(msg)
+			if sample == nil {
+				return false
+			}
+			ok, _ := rule.Matches(sample)
+			return ok
+		}, func(ctx *client.MessageContext) {
+			e.run(ctx, rule)
+		})
+	}
+	return dispatcher
+}
+
+// sample caches the sample on the message since every rule needs it.
+func (e *Engine) sample(msg *client.Message) *Sample {
+	if v, ok := msg.Get(sampleKey); ok {
+		return v.(*Sample)
+	}
+	sample := e.env.Sample(msg)
+	msg.Set(sampleKey, sample)
+	return sample
+}
+
+// Watch reloads the rules when the file changes until done is closed.
+func (e *Engine) Watch(done <-chan struct{}) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			stat, err := os.Stat(e.fileName)
+			if err != nil {
+				continue
+			}
+			e.mu.RLock()
+			changed := !stat.ModTime().Equal(e.modTime)
+			e.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err = e.Reload(); err != nil {
+				// keep the old rules and wait for the next change
+				fmt.Println("reload rules err:", err)
+				e.mu.Lock()
+				e.modTime = stat.ModTime()
+				e.mu.Unlock()
+			}
+		}
+	}
+}
+
+// Dispatch implements client.MessageDispatcher.
+func (e *Engine) Dispatch(msg *client.Message) {
+	e.mu.RLock()
+	dispatcher := e.dispatcher
+	e.mu.RUnlock()
+	dispatcher.Dispatch(msg)
+}
+
+// Metrics returns the timing of each rule's actions since the engine started.
+func (e *Engine) Metrics() []client.HandlerStats {
+	return e.metrics.Stats()
+}
+
+func (e *Engine) Rules() []*Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	rules := make([]*Rule, len(e.rules))
+	copy(rules, e.rules)
+	return rules
+}
+
+// SetDisabled enables or disables the rule and writes it back to the file.
+// The rules are never changed in place, the ones returned by Rules and
+// read by the running dispatcher stay as they were.
+func (e *Engine) SetDisabled(name string, disabled bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var found bool
+	rules := make([]*Rule, len(e.rules))
+	for i, rule := range e.rules {
+		if rule.Name == name {
+			changed := *rule
+			changed.Disabled = disabled
+			rule = &changed
+			found = true
+		}
+		rules[i] = rule
+	}
+	if !found {
+		return fmt.Errorf("no such rule %s", name)
+	}
+	b, err := json.MarshalIndent(file{Rules: rules}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(e.fileName, b, 0666); err != nil {
+		return err
+	}
+	if stat, err := os.Stat(e.fileName); err == nil {
+		e.modTime = stat.ModTime()
+	}
+	e.rules = rules
+	e.setDispatcher(e.newDispatcher(rules))
+	return nil
+}
+
+// TestResult is a rule matching a sample and what its actions would do.
+type TestResult struct {
+	Rule    *Rule
+	Actions []string
+}
+
+// Test matches s against the enabled rules in order without doing the actions.
+func (e *Engine) Test(s *Sample) []*TestResult {
+	var results []*TestResult
+	for _, rule := range e.Rules() {
+		if rule.Disabled {
+			continue
+		}
+		ok, groups := rule.Matches(s)
+		if !ok {
+			continue
+		}
+		data := &TemplateData{Sample: s, Rule: rule.Name, Groups: groups}
+		result := &TestResult{Rule: rule}
+		for _, action := range rule.Actions {
+			result.Actions = append(result.Actions, describe(action, data))
+		}
+		results = append(results, result)
+		if rule.Stop {
+			break
+		}
+	}
+	return results
+}
+
+func render(text string, data *TemplateData) (string, error) {
+	tpl, err := template.New("action").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buffer bytes.Buffer
+	if err = tpl.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func describe(action *Action, data *TemplateData) string {
+	switch action.Type {
+	case ActionReply:
+		rendered, err := render(action.Text, data)
+		if err != nil {
+			return fmt.Sprintf("%s: %v", action.Type, err)
+		}
+		return fmt.Sprintf("%s: %s", action.Type, rendered)
+	case ActionCommand:
+		var env []string
+		for _, kv := range commandEnv(data) {
+			env = append(env, strconv.Quote(kv))
+		}
+		return fmt.Sprintf("%s: %s with %s", action.Type, action.Command, strings.Join(env, " "))
+	case ActionReplyFile:
+		return fmt.Sprintf("%s: %s", action.Type, action.File)
+	case ActionForward:
+		return fmt.Sprintf("%s: %s", action.Type, strings.Join(action.Targets, ", "))
+	}
+	return fmt.Sprintf("%s: %s", action.Type, action.Tag)
+}
+
+func (e *Engine) run(ctx *client.MessageContext, rule *Rule) {
+	sample := e.sample(ctx.Message)
+	_, groups := rule.Matches(sample)
+	data := &TemplateData{Sample: sample, Rule: rule.Name, Groups: groups}
+	for _, action := range rule.Actions {
+		if err := e.do(ctx.Message, action, data); err != nil {
+			fmt.Printf("rule %s %s err: %v\n", rule.Name, action.Type, err)
+		}
+	}
+	if rule.Stop {
+		ctx.Abort()
+	}
+}
+
+func (e *Engine) do(msg *client.Message, action *Action, data *TemplateData) error {
+	switch action.Type {
+	case ActionReply:
+		text, err := render(action.Text, data)
+		if err != nil {
+			return err
+		}
+		return e.env.Reply(msg, text)
+	case ActionReplyFile:
+		return e.env.ReplyFile(msg, action.File)
+	case ActionForward:
+		return e.env.Forward(msg, action.Targets)
+	case ActionTag:
+		return e.env.Tag(msg, action.Tag)
+	case ActionCommand:
+		output, err := runCommand(action.Command, data)
+		if err != nil || output == "" {
+			return err
+		}
+		return e.env.Reply(msg, output)
+	}
+	return fmt.Errorf("unknown action %q", action.Type)
+}
+
+// commandEnv is the message passed to commands.
+func commandEnv(data *TemplateData) []string {
+	return []string{
+		"WX_RULE=" + data.Rule,
+		"WX_SENDER=" + data.Sender,
+		"WX_GROUP=" + data.Group,
+		"WX_TEXT=" + data.Text,
+		"WX_TYPE=" + data.Type,
+	}
+}
+
+// runCommand runs command by the shell with the message in WX_* environment variables.
+// The command is not a template: the message is untrusted and must not become shell code.
+func runCommand(command string, data *TemplateData) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), commandEnv(data)...)
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
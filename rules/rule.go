@@ -0,0 +1,218 @@
+// Package rules replies to, forwards or tags incoming messages by the rules of a json file.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	ActionReply     = "reply"      // reply Text
+	ActionReplyFile = "reply_file" // reply the image, video or file at File
+	ActionForward   = "forward"    // forward the message to Targets, exact contact names or tag:/file: specs
+	ActionTag       = "tag"        // tag the friend or group of the message with Tag
+	ActionCommand   = "command"    // run Command by the shell with the message in WX_* variables, its output is replied
+)
+
+// Match is the conditions of a rule, a message matches when all non-empty conditions match.
+// Lists match when any item matches.
+type Match struct {
+	Senders  []string // sender names, in groups the member name
+	Groups   []string // group names, only group messages match when set
+	Keywords []string // text contains a keyword
+	Regex    string   // text matches the regular expression
+	Types    []string // message types as in export.Kinds
+	At       bool     // only group messages that @ me
+	Time     string   // time window like "09:00-18:00", may cross midnight
+	Days     []int    // days of the week, 0 is Sunday
+}
+
+// Action is done for a matched message, Text is a text/template executed with TemplateData.
+// Command is run as is, it reads the message from $WX_RULE, $WX_SENDER, $WX_GROUP, $WX_TEXT and $WX_TYPE.
+type Action struct {
+	Type    string
+	Text    string   `json:",omitempty"`
+	File    string   `json:",omitempty"`
+	Targets []string `json:",omitempty"`
+	Tag     string   `json:",omitempty"`
+	Command string   `json:",omitempty"`
+}
+
+type Rule struct {
+	Name     string
+	Disabled bool `json:",omitempty"`
+	Stop     bool `json:",omitempty"` // skip the later rules once matched
+	Match    Match
+	Actions  []*Action
+
+	regex         *regexp.Regexp
+	start, end    int // minutes of the day of Time
+	hasTimeWindow bool
+}
+
+// Sample is what rules see of a message.
+type Sample struct {
+	Sender string
+	Group  string // empty if not a group message
+	Text   string
+	Type   string
+	At     bool
+	Time   time.Time
+}
+
+// TemplateData is passed to the reply templates and commands,
+// Groups are the submatches of Match.Regex.
+type TemplateData struct {
+	*Sample
+	Rule   string
+	Groups []string
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// compile checks the rule and prepares its regex and time window.
+func (r *Rule) compile() error {
+	if r.Name == "" {
+		return errors.New("rule name required")
+	}
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("rule %s: no actions", r.Name)
+	}
+	for _, action := range r.Actions {
+		if err := action.check(); err != nil {
+			return fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+	}
+	if r.Match.Regex != "" {
+		regex, err := regexp.Compile(r.Match.Regex)
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		r.regex = regex
+	}
+	if r.Match.Time != "" {
+		parts := strings.Split(r.Match.Time, "-")
+		if len(parts) != 2 {
+			return fmt.Errorf("rule %s: invalid time window %q", r.Name, r.Match.Time)
+		}
+		var err error
+		if r.start, err = parseClock(parts[0]); err != nil {
+			return fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		if r.end, err = parseClock(parts[1]); err != nil {
+			return fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		r.hasTimeWindow = true
+	}
+	return nil
+}
+
+func (a *Action) check() error {
+	switch a.Type {
+	case ActionReply:
+		if a.Text == "" {
+			return errors.New("reply without text")
+		}
+	case ActionReplyFile:
+		if a.File == "" {
+			return errors.New("reply_file without file")
+		}
+	case ActionForward:
+		if len(a.Targets) == 0 {
+			return errors.New("forward without targets")
+		}
+	case ActionTag:
+		if a.Tag == "" {
+			return errors.New("tag without tag")
+		}
+	case ActionCommand:
+		if a.Command == "" {
+			return errors.New("command without command")
+		}
+		if strings.Contains(a.Command, "{{") {
+			return errors.New("command is not a template, read the message from $WX_* variables")
+		}
+	default:
+		return fmt.Errorf("unknown action %q", a.Type)
+	}
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) inTimeWindow(t time.Time) bool {
+	if len(r.Match.Days) > 0 {
+		var ok bool
+		for _, day := range r.Match.Days {
+			if time.Weekday(day) == t.Weekday() {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if !r.hasTimeWindow {
+		return true
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if r.start <= r.end {
+		return minute >= r.start && minute < r.end
+	}
+	return minute >= r.start || minute < r.end
+}
+
+// Matches reports whether s matches the rule, with the submatches of Match.Regex.
+func (r *Rule) Matches(s *Sample) (bool, []string) {
+	m := r.Match
+	if len(m.Senders) > 0 && !containsFold(m.Senders, s.Sender) {
+		return false, nil
+	}
+	if len(m.Groups) > 0 && (s.Group == "" || !containsFold(m.Groups, s.Group)) {
+		return false, nil
+	}
+	if len(m.Types) > 0 && !containsFold(m.Types, s.Type) {
+		return false, nil
+	}
+	if m.At && !s.At {
+		return false, nil
+	}
+	if len(m.Keywords) > 0 {
+		var ok bool
+		text := strings.ToLower(s.Text)
+		for _, keyword := range m.Keywords {
+			if strings.Contains(text, strings.ToLower(keyword)) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	if !r.inTimeWindow(s.Time) {
+		return false, nil
+	}
+	if r.regex == nil {
+		return true, nil
+	}
+	groups := r.regex.FindStringSubmatch(s.Text)
+	return groups != nil, groups
+}
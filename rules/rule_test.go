@@ -0,0 +1,136 @@
+package rules
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+	"wx-cli/client"
+)
+
+func TestRuleMatches(t *testing.T) {
+	rule := &Rule{
+		Name: "weekday work hours",
+		Match: Match{
+			Groups:   []string{"Team"},
+			Keywords: []string{"deploy"},
+			Regex:    `deploy (\w+)`,
+			Time:     "09:00-18:00",
+			Days:     []int{1, 2, 3, 4, 5},
+		},
+		Actions: []*Action{{Type: ActionReply, Text: "deploying {{index .Groups 1}}"}},
+	}
+	if err := rule.compile(); err != nil {
+		t.Fatal(err)
+	}
+	// 2026-10-19 is a Monday
+	monday := time.Date(2026, 10, 19, 10, 0, 0, 0, time.Local)
+	sample := &Sample{Sender: "alice", Group: "team", Text: "please Deploy api", Type: "text", Time: monday}
+	ok, groups := rule.Matches(sample)
+	if ok {
+		t.Error("regex is case sensitive, should not match")
+	}
+	sample.Text = "please deploy api"
+	if ok, groups = rule.Matches(sample); !ok || groups[1] != "api" {
+		t.Errorf("Matches = %v %v, want match with group api", ok, groups)
+	}
+	for _, s := range []Sample{
+		{Group: "", Text: sample.Text, Time: monday},
+		{Group: "team", Text: sample.Text, Time: monday.Add(9 * time.Hour)},
+		{Group: "team", Text: sample.Text, Time: monday.AddDate(0, 0, -1)},
+	} {
+		if ok, _ := rule.Matches(&s); ok {
+			t.Errorf("%+v should not match", s)
+		}
+	}
+}
+
+func TestTimeWindowCrossMidnight(t *testing.T) {
+	rule := &Rule{Name: "night", Match: Match{Time: "22:00-07:00"}, Actions: []*Action{{Type: ActionTag, Tag: "night"}}}
+	if err := rule.compile(); err != nil {
+		t.Fatal(err)
+	}
+	for hour, want := range map[int]bool{23: true, 3: true, 7: false, 12: false} {
+		s := &Sample{Time: time.Date(2026, 1, 1, hour, 0, 0, 0, time.Local)}
+		if ok, _ := rule.Matches(s); ok != want {
+			t.Errorf("%02d:00 Matches = %v, want %v", hour, ok, want)
+		}
+	}
+}
+
+type recordEnv struct {
+	replies []string
+}
+
+func (e *recordEnv) Sample(msg *client.Message) *Sample {
+	return &Sample{Sender: "alice", Text: msg.Content, Type: "text", Time: time.Now()}
+}
+func (e *recordEnv) Reply(msg *client.Message, text string) error {
+	e.replies = append(e.replies, text)
+	return nil
+}
+func (e *recordEnv) ReplyFile(msg *client.Message, filePath string) error { return nil }
+func (e *recordEnv) Forward(msg *client.Message, targets []string) error  { return nil }
+func (e *recordEnv) Tag(msg *client.Message, tag string) error            { return nil }
+
+func TestEngine(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "rules.json")
+	content := `{"Rules": [
+		{"Name": "hello", "Stop": true, "Match": {"Keywords": ["hello"]}, "Actions": [{"Type": "reply", "Text": "hi {{.Sender}}"}]},
+		{"Name": "any", "Match": {}, "Actions": [{"Type": "reply", "Text": "got it"}]}
+	]}`
+	if err := ioutil.WriteFile(fileName, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	env := &recordEnv{}
+	e, err := NewEngine(fileName, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.dispatcher.SetAsync(false)
+	e.Dispatch(&client.Message{Content: "hello there"})
+	if len(env.replies) != 1 || env.replies[0] != "hi alice" {
+		t.Errorf("replies = %v, want only the first rule", env.replies)
+	}
+	if stats := e.Metrics(); len(stats) != 1 || stats[0].Name != "hello" || stats[0].Calls != 1 {
+		t.Errorf("metrics = %+v, want one call of hello", stats)
+	}
+
+	if results := e.Test(&Sample{Sender: "bob", Text: "hello"}); len(results) != 1 || results[0].Actions[0] != "reply: hi bob" {
+		t.Errorf("Test = %+v", results)
+	}
+	before := e.Rules()
+	if err = e.SetDisabled("hello", true); err != nil {
+		t.Fatal(err)
+	}
+	if before[0].Disabled || !e.Rules()[0].Disabled {
+		t.Error("SetDisabled changed a rule already handed out")
+	}
+	reloaded, err := NewEngine(fileName, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results := reloaded.Test(&Sample{Text: "hello"}); len(results) != 1 || results[0].Rule.Name != "any" {
+		t.Errorf("disabled rule not saved: %+v", results)
+	}
+}
+
+func TestRunCommandDoesNotRenderMessage(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "injected")
+	data := &TemplateData{Sample: &Sample{Sender: "mallory", Text: "; touch " + marker + " {{.Sender}}"}, Rule: "echo"}
+	output, err := runCommand(`printf '%s' "$WX_TEXT"`, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != data.Text {
+		t.Errorf("output = %q, want the text as is", output)
+	}
+	if _, err = ioutil.ReadFile(marker); err == nil {
+		t.Error("the message text was run by the shell")
+	}
+
+	action := &Action{Type: ActionCommand, Command: "echo {{.Text}}"}
+	if err = action.check(); err == nil {
+		t.Error("a templated command should be rejected")
+	}
+}
@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// 2026-10-16 is a Friday
+	from := time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local)
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 16, 9, 31, 0, 0, time.Local)},
+		{"0 9 * * 1-5", time.Date(2026, 10, 19, 9, 0, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2026, 10, 16, 9, 45, 0, 0, time.Local)},
+		{"0 0 1 1 *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.Local)},
+		{"30 8 * * 0", time.Date(2026, 10, 18, 8, 30, 0, 0, time.Local)},
+		{"30 8 * * 7", time.Date(2026, 10, 18, 8, 30, 0, 0, time.Local)},
+		{"0 12 20 * 5", time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)},
+		{"5/20 10 * * *", time.Date(2026, 10, 16, 10, 5, 0, 0, time.Local)},
+	}
+	for _, c := range cases {
+		cron, err := ParseCron(c.spec)
+		if err != nil {
+			t.Errorf("ParseCron(%q) err: %v", c.spec, err)
+			continue
+		}
+		if got := cron.Next(from); !got.Equal(c.want) {
+			t.Errorf("ParseCron(%q).Next = %v, want %v", c.spec, got, c.want)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) should fail", spec)
+		}
+	}
+}
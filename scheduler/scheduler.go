@@ -0,0 +1,244 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Run is one delivery of a job.
+type Run struct {
+	Time  int64
+	Error string
+}
+
+// Job sends Text and/or FilePath to Target once at At, or repeatedly by Cron.
+type Job struct {
+	Id       int
+	Target   string
+	Text     string
+	FilePath string
+	At       int64
+	Cron     string
+	NextRun  int64
+	Done     bool
+	History  []Run
+}
+
+func (j *Job) IsCron() bool {
+	return j.Cron != ""
+}
+
+// schedule computes NextRun after now.
+func (j *Job) schedule(now time.Time) error {
+	if j.Target == "" {
+		return errors.New("target required")
+	}
+	if j.Text == "" && j.FilePath == "" {
+		return errors.New("text or file required")
+	}
+	if !j.IsCron() {
+		if j.At == 0 {
+			return errors.New("time or cron required")
+		}
+		j.NextRun = j.At
+		return nil
+	}
+	c, err := ParseCron(j.Cron)
+	if err != nil {
+		return err
+	}
+	next := c.Next(now)
+	if next.IsZero() {
+		return fmt.Errorf("cron %q never runs", j.Cron)
+	}
+	j.NextRun = next.Unix()
+	return nil
+}
+
+// SendFunc delivers job, the returned error is recorded in Job.History.
+type SendFunc func(job *Job) error
+
+// Scheduler keeps jobs in a json file and sends them when due.
+type Scheduler struct {
+	mu       sync.Mutex
+	Jobs     []*Job
+	LastId   int
+	fileName string
+	send     SendFunc
+}
+
+// New loads the jobs of fileName, a missing file starts with no jobs.
+func New(fileName string, send SendFunc) (*Scheduler, error) {
+	s := &Scheduler{fileName: fileName, send: send}
+	b, err := ioutil.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, job := range s.Jobs {
+		// missed cron runs while offline are skipped, missed one-shot jobs are sent late
+		if !job.Done && job.IsCron() && job.NextRun < now.Unix() {
+			_ = job.schedule(now)
+		}
+	}
+	return s, nil
+}
+
+func (s *Scheduler) save() error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.fileName, b, 0666)
+}
+
+func (s *Scheduler) Add(job *Job) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := job.schedule(time.Now()); err != nil {
+		return nil, err
+	}
+	s.LastId++
+	job.Id = s.LastId
+	s.Jobs = append(s.Jobs, job)
+	return job, s.save()
+}
+
+func (s *Scheduler) find(id int) (*Job, bool) {
+	for _, job := range s.Jobs {
+		if job.Id == id {
+			return job, true
+		}
+	}
+	return nil, false
+}
+
+// Get returns a copy of the job.
+func (s *Scheduler) Get(id int) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.find(id)
+	if !ok {
+		return nil, false
+	}
+	j := *job
+	return &j, true
+}
+
+// Edit applies f to a copy of the job and reschedules it,
+// the job is kept unchanged if f or the schedule fails.
+func (s *Scheduler) Edit(id int, f func(job *Job) error) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.find(id)
+	if !ok {
+		return nil, fmt.Errorf("no such job #%d", id)
+	}
+	edited := *job
+	if err := f(&edited); err != nil {
+		return nil, err
+	}
+	if err := edited.schedule(time.Now()); err != nil {
+		return nil, err
+	}
+	edited.Done = false
+	*job = edited
+	return job, s.save()
+}
+
+func (s *Scheduler) Cancel(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, job := range s.Jobs {
+		if job.Id == id {
+			s.Jobs = append(s.Jobs[:i], s.Jobs[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("no such job #%d", id)
+}
+
+// List returns copies of the jobs ordered by next run.
+func (s *Scheduler) List() []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]*Job, len(s.Jobs))
+	for i, job := range s.Jobs {
+		j := *job
+		jobs[i] = &j
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if jobs[i].Done != jobs[j].Done {
+			return !jobs[i].Done
+		}
+		return jobs[i].NextRun < jobs[j].NextRun
+	})
+	return jobs
+}
+
+// due takes the jobs to run at now and moves them to their next run,
+// so a slow delivery never sends the same run twice.
+func (s *Scheduler) due(now time.Time) []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []*Job
+	for _, job := range s.Jobs {
+		if job.Done || job.NextRun > now.Unix() {
+			continue
+		}
+		j := *job
+		jobs = append(jobs, &j)
+		if job.IsCron() {
+			_ = job.schedule(now)
+		} else {
+			job.Done = true
+		}
+	}
+	return jobs
+}
+
+func (s *Scheduler) record(id int, run Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.find(id); ok {
+		job.History = append(job.History, run)
+		_ = s.save()
+	}
+}
+
+// RunDue sends all jobs due at now.
+func (s *Scheduler) RunDue(now time.Time) {
+	for _, job := range s.due(now) {
+		run := Run{Time: now.Unix()}
+		if err := s.send(job); err != nil {
+			run.Error = err.Error()
+		}
+		s.record(job.Id, run)
+	}
+}
+
+// Run checks for due jobs every second until done is closed.
+func (s *Scheduler) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			s.RunDue(now)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRunDue(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "schedule.json")
+	var sent []int
+	send := func(job *Job) error {
+		sent = append(sent, job.Id)
+		if job.Target == "bad" {
+			return errors.New("send failed")
+		}
+		return nil
+	}
+	s, err := New(fileName, send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	once, err := s.Add(&Job{Target: "alice", Text: "hi", At: now.Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, _ := s.Add(&Job{Target: "bad", Text: "hi", At: now.Add(time.Minute).Unix()})
+	cron, err := s.Add(&Job{Target: "group", Text: "daily", Cron: "0 9 * * *"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.RunDue(now)
+	if len(sent) != 0 {
+		t.Fatalf("sent %v before due", sent)
+	}
+	s.RunDue(now.Add(2 * time.Minute))
+	s.RunDue(now.Add(3 * time.Minute))
+	if len(sent) != 2 {
+		t.Fatalf("sent %v, want one-shot jobs sent exactly once", sent)
+	}
+
+	job, _ := s.Get(failed.Id)
+	if len(job.History) != 1 || job.History[0].Error == "" {
+		t.Errorf("failed delivery not recorded: %+v", job.History)
+	}
+
+	loaded, err := New(fileName, send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, ok := loaded.Get(once.Id); !ok || !job.Done || len(job.History) != 1 {
+		t.Errorf("one-shot job not persisted: %+v", job)
+	}
+	if job, ok := loaded.Get(cron.Id); !ok || job.Done || job.NextRun <= now.Unix() {
+		t.Errorf("cron job not persisted: %+v", job)
+	}
+	if err = loaded.Cancel(cron.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.Get(cron.Id); ok {
+		t.Error("canceled job still listed")
+	}
+}
@@ -3,21 +3,44 @@ package storage
 import (
 	"encoding/json"
 	"io/ioutil"
+	"strconv"
+	"sync"
 	"wx-cli/client"
 )
 
 type Messages []*client.Message
 
+type Recall struct {
+	OldMsgId   string
+	Original   *client.Message
+	Message    *client.Message
+	ReplaceMsg string
+	RecalledAt int64
+}
+
+type Recalls []*Recall
+
 type Cache struct {
-	messages   Messages
-	viewMsgCur int
-	fileName   string
+	mu             sync.RWMutex
+	messages       Messages
+	msgIndex       map[string]*client.Message
+	recalls        Recalls
+	recallMap      map[string]*Recall
+	friendRequests FriendRequests
+	sent           SentMessages
+	postings       map[string][]int
+	searchTexts    []string
+	viewMsgCur     int
+	fileName       string
 }
 
 func NewCache(fileName string) *Cache {
 	return &Cache{
-		messages: make([]*client.Message, 0),
-		fileName: fileName,
+		messages:  make([]*client.Message, 0),
+		msgIndex:  make(map[string]*client.Message),
+		recallMap: make(map[string]*Recall),
+		postings:  make(map[string][]int),
+		fileName:  fileName,
 	}
 }
 
@@ -33,19 +56,143 @@ func NewCacheFromFile(fileName string) (*Cache, error) {
 		return nil, err
 	}
 	c.fileName = fileName
+	c.rebuild()
 	return c, nil
 }
 
-func (c *Cache) StoreMessage(msg *client.Message) {
+// rebuild restores the indexes and the recall links of the loaded messages,
+// an original is stored before its recall so the links are found in order.
+func (c *Cache) rebuild() {
+	c.msgIndex = make(map[string]*client.Message)
+	c.recalls = nil
+	c.recallMap = make(map[string]*Recall)
+	c.postings = make(map[string][]int)
+	c.searchTexts = nil
+	for pos, msg := range c.messages {
+		c.msgIndex[msg.MsgId] = msg
+		c.index(pos, msg, nil)
+		if msg.IsRecalled() {
+			c.storeRecall(msg)
+		}
+	}
+}
+
+// StoreMessage stores msg and indexes it for Search together with names,
+// such as the sender and group names which only the caller can resolve.
+func (c *Cache) StoreMessage(msg *client.Message, names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.storeMessage(msg, names)
+}
+
+func (c *Cache) storeMessage(msg *client.Message, names []string) {
+	// a sent message may come back from webwxsync
+	if _, ok := c.msgIndex[msg.MsgId]; ok && msg.MsgId != "" {
+		return
+	}
 	c.messages = append(c.messages, msg)
+	c.msgIndex[msg.MsgId] = msg
+	c.index(len(c.messages)-1, msg, names)
+	if msg.IsRecalled() {
+		c.storeRecall(msg)
+	}
+	if msg.IsFriendAdd() {
+		c.storeFriendRequest(msg)
+	}
+}
+
+func (c *Cache) storeRecall(msg *client.Message) {
+	revokeMsg, err := msg.RevokeMsg()
+	if err != nil {
+		return
+	}
+	oldMsgId := strconv.FormatInt(revokeMsg.RevokeMsg.OldMsgId, 10)
+	recall := &Recall{
+		OldMsgId:   oldMsgId,
+		Original:   c.msgIndex[oldMsgId],
+		Message:    msg,
+		ReplaceMsg: revokeMsg.RevokeMsg.ReplaceMsg,
+		RecalledAt: msg.CreateTime,
+	}
+	c.recalls = append(c.recalls, recall)
+	c.recallMap[oldMsgId] = recall
+	c.recallMap[msg.MsgId] = recall
+}
+
+func (c *Cache) GetMessage(msgId string) (*client.Message, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	msg, ok := c.msgIndex[msgId]
+	return msg, ok
+}
+
+// GetRecall returns the recall record of msgId,
+// msgId can be either the original message or the revoke message.
+func (c *Cache) GetRecall(msgId string) (*Recall, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	recall, ok := c.recallMap[msgId]
+	return recall, ok
+}
+
+func (c *Cache) RecentRecalls(count int) Recalls {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if count <= 0 || count > len(c.recalls) {
+		count = len(c.recalls)
+	}
+	recalls := make(Recalls, count)
+	copy(recalls, c.recalls[len(c.recalls)-count:])
+	return recalls
+}
+
+// MessageAt returns the nth message, n starts from 1.
+func (c *Cache) MessageAt(n int) (*client.Message, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if n <= 0 || n > len(c.messages) {
+		return nil, false
+	}
+	return c.messages[n-1], true
+}
+
+// UnreadMessagesFrom returns the messages since last call and the position of the first one.
+func (c *Cache) UnreadMessagesFrom() (Messages, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cur := c.viewMsgCur
+	c.viewMsgCur = len(c.messages)
+	return c.messages[cur:], cur + 1
 }
 
 func (c *Cache) AllMessages() Messages {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.messages
 }
 
+// CacheSize counts what the cache holds.
+type CacheSize struct {
+	Messages       int
+	Recalls        int
+	FriendRequests int
+	Sent           int
+	Terms          int // distinct search tokens
+}
+
+func (c *Cache) Size() CacheSize {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return CacheSize{
+		Messages:       len(c.messages),
+		Recalls:        len(c.recalls),
+		FriendRequests: len(c.friendRequests),
+		Sent:           len(c.sent),
+		Terms:          len(c.postings),
+	}
+}
+
 func (c *Cache) UnreadMessages() Messages {
-	cur := c.viewMsgCur
-	c.viewMsgCur = len(c.messages)
-	return c.messages[cur:]
+	messages, _ := c.UnreadMessagesFrom()
+	return messages
 }
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"wx-cli/client"
+)
+
+func TestStoreRecall(t *testing.T) {
+	c := NewCache("")
+	original := &client.Message{MsgId: "1001", MsgType: client.MsgTypeText, Content: "hello"}
+	revoke := &client.Message{
+		MsgId:      "1002",
+		MsgType:    client.MsgTypeRecalled,
+		CreateTime: 1600000000,
+		Content:    `<sysmsg type="revokemsg"><revokemsg><oldmsgid>1001</oldmsgid><replacemsg>"x" recalled a message</replacemsg></revokemsg></sysmsg>`,
+	}
+	c.StoreMessage(original)
+	c.StoreMessage(revoke)
+
+	recall, ok := c.GetRecall("1001")
+	if !ok {
+		t.Fatal("original message not marked recalled")
+	}
+	if recall.Original != original || recall.Message != revoke {
+		t.Error("recall not linked to original")
+	}
+	if recall.RecalledAt != revoke.CreateTime {
+		t.Errorf("RecalledAt = %d, want %d", recall.RecalledAt, revoke.CreateTime)
+	}
+	if recalls := c.RecentRecalls(5); len(recalls) != 1 {
+		t.Errorf("RecentRecalls = %d, want 1", len(recalls))
+	}
+	if size := c.Size(); size.Messages != 2 || size.Recalls != 1 {
+		t.Errorf("Size = %+v, want 2 messages and 1 recall", size)
+	}
+
+	// loaded messages get their recall links back
+	loaded := &Cache{messages: Messages{original, revoke}}
+	loaded.rebuild()
+	if recall, ok := loaded.GetRecall("1001"); !ok || recall.Original != original || recall.Message != revoke {
+		t.Error("recall not linked after loading")
+	}
+	if size := loaded.Size(); size.Messages != 2 || size.Recalls != 1 {
+		t.Errorf("loaded Size = %+v, want 2 messages and 1 recall", size)
+	}
+}
+
+func TestStoreSentMessage(t *testing.T) {
+	c := NewCache("")
+	for _, content := range []string{"a", "b", "c"} {
+		msg := client.NewSendMessage(client.MsgTypeText, content, "me", "you", "")
+		c.StoreSentMessage(&client.SentMessage{SendMessage: msg})
+	}
+	sent := c.RecentSent(2)
+	if len(sent) != 2 || sent[0].Message.Content != "b" || sent[1].Message.Content != "c" {
+		t.Fatalf("RecentSent(2) = %v", sent)
+	}
+	if !sent[1].Message.CanRevoke() {
+		t.Error("just sent message should be revocable")
+	}
+	if !c.SetRevoked(sent[1].Id) {
+		t.Fatal("SetRevoked failed")
+	}
+	if got, _ := c.GetSent(3); !got.Revoked {
+		t.Error("message not marked revoked")
+	}
+
+	// sent messages are listed and searched with the received ones
+	c.StoreMessage(&client.Message{MsgId: "r1", Content: "received"})
+	sentMsg := client.NewSendMessage(client.MsgTypeText, "deploy done", "me", "you", "")
+	c.StoreSentMessage(&client.SentMessage{SendMessage: sentMsg, MsgId: "s1"}, "you")
+	c.StoreMessage(&client.Message{MsgId: "s1", Content: "deploy done"})
+	all := c.AllMessages()
+	if len(all) != 5 || all[4].MsgId != "s1" || all[4].FromUserName != "me" {
+		t.Fatalf("AllMessages = %d, want the sent messages in order and no duplicate", len(all))
+	}
+	if results := c.Search("deploy you"); len(results) != 1 || results[0].Message.MsgId != "s1" {
+		t.Errorf("Search = %v, want the sent message", results)
+	}
+}
+
+func TestHandleFriendRequestOnce(t *testing.T) {
+	c := NewCache("")
+	c.storeFriendRequest(&client.Message{MsgId: "f1"})
+
+	var wg sync.WaitGroup
+	var handled int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.HandleFriendRequest(1, FriendRequestAccepted); err == nil {
+				atomic.AddInt32(&handled, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if handled != 1 {
+		t.Fatalf("request handled %d times, want once", handled)
+	}
+	if _, err := c.HandleFriendRequest(1, FriendRequestIgnored); err == nil {
+		t.Error("handled request handled again")
+	}
+	if _, err := c.HandleFriendRequest(2, FriendRequestIgnored); err == nil {
+		t.Error("missing request handled")
+	}
+	// a failed accept puts it back to pending
+	c.SetFriendRequestStatus(1, FriendRequestPending)
+	if requests := c.FriendRequests(true); len(requests) != 1 || requests[0].HandledAt != 0 {
+		t.Errorf("pending requests = %v", requests)
+	}
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+	"wx-cli/client"
+)
+
+// Directory is the local copy of contacts and groups,
+// persisted so that names can be resolved without fetching members.
+// Contacts are keyed by UserName, which only lives as long as the login session,
+// so they are kept on hot login and replaced after a new login; tags are keyed by name and kept.
+type Directory struct {
+	mu        sync.RWMutex
+	Contacts  map[string]*client.User
+	Tags      map[string][]string // contact names by tag
+	UpdatedAt int64
+	fileName  string
+}
+
+func NewDirectory(fileName string) *Directory {
+	return &Directory{
+		Contacts: make(map[string]*client.User),
+		Tags:     make(map[string][]string),
+		fileName: fileName,
+	}
+}
+
+func NewDirectoryFromFile(fileName string) (*Directory, error) {
+	d := &Directory{}
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, d); err != nil {
+		return nil, err
+	}
+	if d.Contacts == nil {
+		d.Contacts = make(map[string]*client.User)
+	}
+	if d.Tags == nil {
+		d.Tags = make(map[string][]string)
+	}
+	d.fileName = fileName
+	return d, nil
+}
+
+func (d *Directory) Save() error {
+	d.mu.RLock()
+	b, err := json.Marshal(d)
+	d.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(d.fileName, b, 0666)
+}
+
+// Replace drops all contacts, including those of earlier sessions, and stores members instead.
+func (d *Directory) Replace(members client.Members) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Contacts = make(map[string]*client.User, len(members))
+	for _, member := range members {
+		c := *member
+		d.Contacts[c.UserName] = &c
+	}
+	d.UpdatedAt = time.Now().Unix()
+}
+
+// Apply merges the contact deltas of webwxsync.
+// A modified contact without member list keeps the cached one.
+func (d *Directory) Apply(modContacts, delContacts []*client.User) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, contact := range modContacts {
+		// the contacts may be shared with client.Self, store a copy
+		c := *contact
+		if old, ok := d.Contacts[c.UserName]; ok && len(c.MemberList) == 0 {
+			c.MemberList = old.MemberList
+		}
+		d.Contacts[c.UserName] = &c
+	}
+	for _, contact := range delContacts {
+		delete(d.Contacts, contact.UserName)
+	}
+	d.UpdatedAt = time.Now().Unix()
+}
+
+func (d *Directory) Get(username string) (*client.User, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	user, ok := d.Contacts[username]
+	return user, ok
+}
+
+// GetMember finds a member of group from the cached member list.
+func (d *Directory) GetMember(groupUserName, username string) (*client.User, bool) {
+	group, ok := d.Get(groupUserName)
+	if !ok {
+		return nil, false
+	}
+	return group.MemberList.GetByUserName(username)
+}
+
+func (d *Directory) Members() client.Members {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	members := make(client.Members, 0, len(d.Contacts))
+	for _, user := range d.Contacts {
+		members = append(members, user)
+	}
+	return members
+}
+
+func (d *Directory) Count() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.Contacts)
+}
+
+// AddTag tags contacts by name, names are kept instead of UserName
+// because UserName changes between sessions.
+func (d *Directory) AddTag(tag string, names ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, name := range names {
+		if !containsString(d.Tags[tag], name) {
+			d.Tags[tag] = append(d.Tags[tag], name)
+		}
+	}
+}
+
+// RemoveTag removes names from tag, or the whole tag if names is empty.
+func (d *Directory) RemoveTag(tag string, names ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(names) == 0 {
+		delete(d.Tags, tag)
+		return
+	}
+	kept := d.Tags[tag][:0]
+	for _, name := range d.Tags[tag] {
+		if !containsString(names, name) {
+			kept = append(kept, name)
+		}
+	}
+	d.Tags[tag] = kept
+}
+
+func (d *Directory) Tagged(tag string) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	names := make([]string, len(d.Tags[tag]))
+	copy(names, d.Tags[tag])
+	return names
+}
+
+func (d *Directory) TagNames() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	tags := make([]string, 0, len(d.Tags))
+	for tag := range d.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
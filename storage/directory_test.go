@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"wx-cli/client"
+)
+
+func TestDirectoryApply(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "contacts.json")
+	d := NewDirectory(fileName)
+	member := &client.User{UserName: "@m", NickName: "member"}
+	d.Replace(client.Members{
+		{UserName: "@a", NickName: "alice"},
+		{UserName: "@@g", NickName: "group", MemberList: client.Members{member}},
+	})
+
+	d.Apply([]*client.User{{UserName: "@@g", NickName: "renamed"}}, []*client.User{{UserName: "@a"}})
+	if _, ok := d.Get("@a"); ok {
+		t.Error("deleted contact still in directory")
+	}
+	if group, _ := d.Get("@@g"); group.NickName != "renamed" {
+		t.Errorf("NickName = %s, want renamed", group.NickName)
+	}
+	if _, ok := d.GetMember("@@g", "@m"); !ok {
+		t.Error("member list lost after modification")
+	}
+
+	if err := d.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := NewDirectoryFromFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Count() != 1 {
+		t.Errorf("Count = %d, want 1", loaded.Count())
+	}
+	if user, ok := loaded.GetMember("@@g", "@m"); !ok || user.NickName != "member" {
+		t.Error("member list not persisted")
+	}
+}
+
+func TestDirectoryReplaceKeepsTags(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "contacts.json")
+	d := NewDirectory(fileName)
+	d.Replace(client.Members{{UserName: "@old", NickName: "alice", RemarkName: "Alice W"}})
+	d.AddTag("team", "Alice W")
+	if err := d.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the next session has new UserNames, remarks come only from its contact list
+	next, err := NewDirectoryFromFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, ok := next.Get("@old"); !ok || user.RemarkName != "Alice W" {
+		t.Errorf("saved contact not loaded: %+v", user)
+	}
+	next.Replace(client.Members{{UserName: "@new", NickName: "alice"}, {UserName: "@bob", NickName: "alice"}})
+	if _, ok := next.Get("@old"); ok {
+		t.Error("contact of the last session still in directory")
+	}
+	if user, ok := next.Get("@new"); !ok || user.RemarkName != "" {
+		t.Errorf("remark guessed from another contact: %+v", user)
+	}
+	if names := next.Tagged("team"); len(names) != 1 || names[0] != "Alice W" {
+		t.Errorf("Tagged = %v", names)
+	}
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+	"wx-cli/client"
+)
+
+type FriendRequestStatus int
+
+const (
+	FriendRequestPending FriendRequestStatus = iota
+	FriendRequestAccepted
+	FriendRequestIgnored
+)
+
+func (s FriendRequestStatus) String() string {
+	switch s {
+	case FriendRequestAccepted:
+		return "Accepted"
+	case FriendRequestIgnored:
+		return "Ignored"
+	}
+	return "Pending"
+}
+
+type FriendRequest struct {
+	Id        int
+	Message   *client.Message
+	Status    FriendRequestStatus
+	HandledAt int64
+}
+
+type FriendRequests []*FriendRequest
+
+func (c *Cache) storeFriendRequest(msg *client.Message) {
+	request := &FriendRequest{
+		Id:      len(c.friendRequests) + 1,
+		Message: msg,
+	}
+	c.friendRequests = append(c.friendRequests, request)
+}
+
+func (c *Cache) FriendRequests(pendingOnly bool) FriendRequests {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	requests := make(FriendRequests, 0, len(c.friendRequests))
+	for _, request := range c.friendRequests {
+		if pendingOnly && request.Status != FriendRequestPending {
+			continue
+		}
+		requests = append(requests, request)
+	}
+	return requests
+}
+
+func (c *Cache) GetFriendRequest(id int) (*FriendRequest, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if id <= 0 || id > len(c.friendRequests) {
+		return nil, false
+	}
+	return c.friendRequests[id-1], true
+}
+
+func (c *Cache) SetFriendRequestStatus(id int, status FriendRequestStatus) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id <= 0 || id > len(c.friendRequests) {
+		return false
+	}
+	c.setFriendRequestStatus(c.friendRequests[id-1], status)
+	return true
+}
+
+// HandleFriendRequest marks the pending request id as status and returns it,
+// the check and the update are done under one lock so a request is handled once.
+func (c *Cache) HandleFriendRequest(id int, status FriendRequestStatus) (*FriendRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id <= 0 || id > len(c.friendRequests) {
+		return nil, fmt.Errorf("no such friend request #%d", id)
+	}
+	request := c.friendRequests[id-1]
+	if request.Status != FriendRequestPending {
+		return nil, fmt.Errorf("friend request #%d is already %s", id, request.Status)
+	}
+	c.setFriendRequestStatus(request, status)
+	return request, nil
+}
+
+func (c *Cache) setFriendRequestStatus(request *FriendRequest, status FriendRequestStatus) {
+	request.Status = status
+	request.HandledAt = 0
+	if status != FriendRequestPending {
+		request.HandledAt = time.Now().Unix()
+	}
+}
@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"wx-cli/client"
+)
+
+// SearchResult is a message matching the query,
+// N is its position as in MessageAt and Text is the indexed text.
+type SearchResult struct {
+	N       int
+	Message *client.Message
+	Text    string
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Tokenize splits text into lower case words, a run of CJK characters,
+// which has no spaces between words, is split into unigrams and bigrams.
+func Tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i := range cjk {
+			tokens = append(tokens, string(cjk[i]))
+			if i+1 < len(cjk) {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// normalize lower cases text and turns every run of punctuation and spaces
+// into one space, the way Tokenize sees the words apart.
+func normalize(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !isCJK(r) && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// queryTokens tokenizes a query, using only the bigrams of CJK runs
+// since they already cover the unigrams.
+func queryTokens(query string) []string {
+	var tokens []string
+	for _, term := range strings.Fields(query) {
+		all := Tokenize(term)
+		for i, token := range all {
+			runes := []rune(token)
+			// a CJK unigram followed by its bigram is redundant
+			if len(runes) == 1 && isCJK(runes[0]) && i+1 < len(all) && strings.HasPrefix(all[i+1], token) && len([]rune(all[i+1])) == 2 {
+				continue
+			}
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// searchText is the text of msg to index: the content of text messages,
+// the title of app messages and the file name of files.
+func searchText(msg *client.Message) string {
+	switch {
+	case msg.IsText() || msg.IsSystem():
+		return msg.Content
+	case msg.IsMedia():
+		data, err := msg.MediaData()
+		if err != nil {
+			return msg.FileName
+		}
+		return strings.TrimSpace(data.AppMsg.Title + " " + data.AppMsg.Des)
+	}
+	return msg.FileName
+}
+
+// index adds the message at pos with the extra names to the inverted index.
+func (c *Cache) index(pos int, msg *client.Message, names []string) {
+	text := searchText(msg)
+	fields := append([]string{text}, names...)
+	doc := strings.Join(fields, "\n")
+	c.searchTexts = append(c.searchTexts, normalize(doc))
+	for _, token := range Tokenize(doc) {
+		postings := c.postings[token]
+		if len(postings) > 0 && postings[len(postings)-1] == pos {
+			continue
+		}
+		c.postings[token] = append(postings, pos)
+	}
+}
+
+// lookup returns the postings of token, words also match as prefix
+// so that "meet" finds "meeting".
+func (c *Cache) lookup(token string) []int {
+	if isCJK([]rune(token)[0]) {
+		return c.postings[token]
+	}
+	var lists [][]int
+	for key, postings := range c.postings {
+		if strings.HasPrefix(key, token) {
+			lists = append(lists, postings)
+		}
+	}
+	if len(lists) == 1 {
+		return lists[0]
+	}
+	seen := make(map[int]bool)
+	var union []int
+	for _, postings := range lists {
+		for _, pos := range postings {
+			if !seen[pos] {
+				seen[pos] = true
+				union = append(union, pos)
+			}
+		}
+	}
+	sort.Ints(union)
+	return union
+}
+
+func intersect(a, b []int) []int {
+	var result []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// Search returns the messages containing every term of query, oldest first.
+// The content, the title of articles and the names given to StoreMessage are searched,
+// an empty query returns every message for filtering.
+func (c *Cache) Search(query string) []*SearchResult {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if strings.TrimSpace(query) == "" {
+		results := make([]*SearchResult, len(c.messages))
+		for pos, msg := range c.messages {
+			results[pos] = &SearchResult{N: pos + 1, Message: msg, Text: searchText(msg)}
+		}
+		return results
+	}
+	tokens := queryTokens(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+	candidates := c.lookup(tokens[0])
+	for _, token := range tokens[1:] {
+		candidates = intersect(candidates, c.lookup(token))
+	}
+	var terms []string
+	for _, term := range strings.Fields(query) {
+		if term = normalize(term); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	var results []*SearchResult
+	for _, pos := range candidates {
+		// the tokens may match apart, check the words of every term appear together
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(c.searchTexts[pos], term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			results = append(results, &SearchResult{N: pos + 1, Message: c.messages[pos], Text: searchText(c.messages[pos])})
+		}
+	}
+	return results
+}
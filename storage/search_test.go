@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"wx-cli/client"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Hello, 明天开会 ok2")
+	want := []string{"hello", "明", "明天", "天", "天开", "开", "开会", "会", "ok2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize = %v, want %v", got, want)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	c := NewCache("")
+	c.StoreMessage(&client.Message{MsgId: "1", MsgType: client.MsgTypeText, Content: "明天上午9点开会"}, "张三")
+	c.StoreMessage(&client.Message{MsgId: "2", MsgType: client.MsgTypeText, Content: "会议改到后天"}, "李四", "项目群")
+	c.StoreMessage(&client.Message{MsgId: "3", MsgType: client.MsgTypeText, Content: "Meeting notes attached"}, "Bob")
+	c.StoreMessage(&client.Message{
+		MsgId:      "4",
+		MsgType:    client.MsgTypeApp,
+		AppMsgType: client.AppMsgTypeUrl,
+		Content:    `<msg><appmsg><title>开会指南</title><des>how to meet</des></appmsg></msg>`,
+	})
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"开会", []string{"1", "4"}},
+		{"会", []string{"1", "2", "4"}},
+		{"项目群", []string{"2"}},
+		{"meeting", []string{"3"}},
+		{"MEET", []string{"3", "4"}},
+		{"开会 张三", []string{"1"}},
+		{"天开", nil},
+		{"notes,", []string{"3"}},
+		{"9点开会!", []string{"1"}},
+		{"notes-attached", []string{"3"}},
+		{"notes-meeting", nil},
+		{"?!", nil},
+		{" ", []string{"1", "2", "3", "4"}},
+	}
+	for _, c2 := range cases {
+		var got []string
+		for _, result := range c.Search(c2.query) {
+			got = append(got, result.Message.MsgId)
+		}
+		if !reflect.DeepEqual(got, c2.want) {
+			t.Errorf("Search(%q) = %v, want %v", c2.query, got, c2.want)
+		}
+	}
+	if results := c.Search("改到"); len(results) != 1 || results[0].N != 2 {
+		t.Errorf("Search position = %+v, want N 2", results)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"time"
+	"wx-cli/client"
+)
+
+type Sent struct {
+	Id        int
+	Message   *client.SentMessage
+	Revoked   bool
+	RevokedAt int64
+}
+
+type SentMessages []*Sent
+
+// StoreSentMessage keeps msg for revoking and stores it next to the received messages,
+// indexed for Search together with names like StoreMessage.
+func (c *Cache) StoreSentMessage(msg *client.SentMessage, names ...string) *Sent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sent := &Sent{
+		Id:      len(c.sent) + 1,
+		Message: msg,
+	}
+	c.sent = append(c.sent, sent)
+	c.storeMessage(msg.Message(), names)
+	return sent
+}
+
+// RecentSent returns the last count sent messages, oldest first.
+func (c *Cache) RecentSent(count int) SentMessages {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if count <= 0 || count > len(c.sent) {
+		count = len(c.sent)
+	}
+	sent := make(SentMessages, count)
+	copy(sent, c.sent[len(c.sent)-count:])
+	return sent
+}
+
+func (c *Cache) GetSent(id int) (*Sent, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if id <= 0 || id > len(c.sent) {
+		return nil, false
+	}
+	return c.sent[id-1], true
+}
+
+func (c *Cache) SetRevoked(id int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id <= 0 || id > len(c.sent) {
+		return false
+	}
+	sent := c.sent[id-1]
+	sent.Revoked = true
+	sent.RevokedAt = time.Now().Unix()
+	return true
+}
@@ -1,7 +1,45 @@
 package util
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestGetCurrentPath(t *testing.T) {
 	t.Log(GetCurrentPath())
 }
+
+func TestSplitArgs(t *testing.T) {
+	cases := []struct {
+		line string
+		want []string
+	}{
+		{`to alice`, []string{"to", "alice"}},
+		{`to  "Zhang San"  `, []string{"to", "Zhang San"}},
+		{`send 'it''s' a\ b`, []string{"send", "its", "a b"}},
+		{`send "say \"hi\""`, []string{"send", `say "hi"`}},
+		{`send ""`, []string{"send", ""}},
+	}
+	for _, c := range cases {
+		got, err := SplitArgs(c.line)
+		if err != nil {
+			t.Errorf("SplitArgs(%q) err: %v", c.line, err)
+			continue
+		}
+		if strings.Join(got, "|") != strings.Join(c.want, "|") || len(got) != len(c.want) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", c.line, got, c.want)
+		}
+	}
+	if _, err := SplitArgs(`to "alice`); err == nil {
+		t.Error("unterminated quote should fail")
+	}
+}
+
+func TestQuoteArg(t *testing.T) {
+	for _, arg := range []string{"alice", "Zhang San", `a"b\c`, ""} {
+		got, err := SplitArgs(QuoteArg(arg))
+		if err != nil || len(got) != 1 || got[0] != arg {
+			t.Errorf("QuoteArg(%q) round trip = %q, %v", arg, got, err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	retryBase     = 5 * time.Second
+	retryMax      = 10 * time.Minute
+	checkInterval = time.Second
+)
+
+// Forwarder queues payloads for the matching webhooks and posts them.
+type Forwarder struct {
+	webhooks map[string]*Config
+	order    []string
+	queue    *queue
+	client   *http.Client
+}
+
+// NewForwarder loads the queue persisted at queueFile,
+// dead letters are appended to deadLetterFile.
+func NewForwarder(configs []*Config, queueFile, deadLetterFile string) (*Forwarder, error) {
+	f := &Forwarder{
+		webhooks: make(map[string]*Config),
+		client:   &http.Client{Timeout: defaultTimeout},
+	}
+	for _, cfg := range configs {
+		if cfg.Name == "" || cfg.URL == "" {
+			return nil, fmt.Errorf("webhook %q: name and url required", cfg.Name)
+		}
+		if _, ok := f.webhooks[cfg.Name]; ok {
+			return nil, fmt.Errorf("duplicate webhook %s", cfg.Name)
+		}
+		f.webhooks[cfg.Name] = cfg
+		f.order = append(f.order, cfg.Name)
+	}
+	q, err := loadQueue(queueFile, deadLetterFile)
+	if err != nil {
+		return nil, err
+	}
+	f.queue = q
+	return f, nil
+}
+
+// Enqueue queues p for every webhook whose filter matches.
+func (f *Forwarder) Enqueue(p *Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var deliveries []*Delivery
+	for _, name := range f.order {
+		if !f.webhooks[name].Filter.Match(p) {
+			continue
+		}
+		deliveries = append(deliveries, &Delivery{
+			Id:        fmt.Sprintf("%s-%s", name, p.MsgId),
+			Webhook:   name,
+			Body:      body,
+			CreatedAt: now.Unix(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return f.queue.push(deliveries...)
+}
+
+func (f *Forwarder) post(cfg *Config, d *Delivery) error {
+	req, err := http.NewRequest(http.MethodPost, cfg.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Wx-Event", "message")
+	req.Header.Set("X-Wx-Delivery", d.Id)
+	req.Header.Set("X-Wx-Timestamp", timestamp)
+	if cfg.Secret != "" {
+		req.Header.Set("X-Wx-Signature", Sign(cfg.Secret, timestamp, d.Body))
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
+
+// backoff doubles the wait after every failed attempt.
+func backoff(attempts int) time.Duration {
+	wait := retryBase
+	for i := 1; i < attempts && wait < retryMax; i++ {
+		wait *= 2
+	}
+	if wait > retryMax {
+		wait = retryMax
+	}
+	return wait
+}
+
+// Flush attempts the deliveries due at now once.
+func (f *Forwarder) Flush(now time.Time) {
+	for _, d := range f.queue.due(now) {
+		// a delivery of a webhook removed from the config goes to the dead letters
+		var maxAttempts int
+		err := fmt.Errorf("webhook %s not configured", d.Webhook)
+		if cfg, ok := f.webhooks[d.Webhook]; ok {
+			maxAttempts = cfg.maxAttempts()
+			err = f.post(cfg, d)
+		}
+		if err == nil {
+			err = f.queue.done(d)
+		} else {
+			err = f.queue.retry(d, err, now, maxAttempts)
+		}
+		if err != nil {
+			fmt.Println("webhook queue err:", err)
+		}
+	}
+}
+
+// Run flushes the queue every second until done is closed.
+func (f *Forwarder) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			f.Flush(now)
+		}
+	}
+}
+
+// Status of a webhook forwarder.
+type Status struct {
+	Webhooks []string
+	Pending  int
+	Dead     int
+}
+
+func (f *Forwarder) Status() (*Status, error) {
+	dead, err := f.queue.deadLetters(false)
+	if err != nil {
+		return nil, err
+	}
+	return &Status{Webhooks: f.order, Pending: f.queue.pending(), Dead: len(dead)}, nil
+}
+
+func (f *Forwarder) DeadLetters() ([]*Delivery, error) {
+	return f.queue.deadLetters(false)
+}
+
+// RequeueDeadLetters moves the dead letters back to the queue and returns their count.
+func (f *Forwarder) RequeueDeadLetters() (int, error) {
+	deliveries, err := f.queue.deadLetters(true)
+	return len(deliveries), err
+}
@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MediaFunc returns the local path of the media of msgId, downloading it if needed.
+type MediaFunc func(msgId string) (string, error)
+
+// SignMedia returns the hex HMAC-SHA256 of msgId, the sig parameter of a media link.
+func SignMedia(secret, msgId string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(msgId))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// MediaURL returns the signed link to the media of msgId served under base.
+func MediaURL(base, secret, msgId string) string {
+	return base + "/media/" + url.PathEscape(msgId) + "?sig=" + SignMedia(secret, msgId)
+}
+
+// MediaHandler serves GET /media/<msgId>?sig=<signature> from local storage,
+// the url put in Payload.MediaURL. Links not signed with secret are refused.
+func MediaHandler(secret string, media MediaFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msgId := strings.TrimPrefix(r.URL.Path, "/media/")
+		if r.Method != http.MethodGet || msgId == "" || strings.Contains(msgId, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		sig := r.URL.Query().Get("sig")
+		if secret == "" || !hmac.Equal([]byte(sig), []byte(SignMedia(secret, msgId))) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		path, err := media(msgId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.ServeFile(w, r, path)
+	})
+}
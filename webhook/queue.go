@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Delivery is a payload waiting to be posted to a webhook.
+type Delivery struct {
+	Id          string
+	Webhook     string
+	Body        json.RawMessage
+	Attempts    int
+	NextAttempt int64
+	LastError   string `json:",omitempty"`
+	CreatedAt   int64
+}
+
+// queue keeps the pending deliveries in a json file and the dead letters,
+// which failed MaxAttempts times, in a json lines file.
+type queue struct {
+	mu         sync.Mutex
+	Pending    []*Delivery
+	fileName   string
+	deadLetter string
+}
+
+func loadQueue(fileName, deadLetter string) (*queue, error) {
+	q := &queue{fileName: fileName, deadLetter: deadLetter}
+	b, err := ioutil.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// save is called with mu held.
+func (q *queue) save() error {
+	b, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(q.fileName, b, 0666)
+}
+
+func (q *queue) push(deliveries ...*Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.Pending = append(q.Pending, deliveries...)
+	return q.save()
+}
+
+// due returns the deliveries to attempt at now.
+func (q *queue) due(now time.Time) []*Delivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var deliveries []*Delivery
+	for _, d := range q.Pending {
+		if d.NextAttempt <= now.Unix() {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries
+}
+
+func (q *queue) remove(d *Delivery) {
+	for i, pending := range q.Pending {
+		if pending == d {
+			q.Pending = append(q.Pending[:i], q.Pending[i+1:]...)
+			return
+		}
+	}
+}
+
+func (q *queue) done(d *Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.remove(d)
+	return q.save()
+}
+
+// retry records the failed attempt and schedules d again after the backoff,
+// or moves it to the dead letters after maxAttempts.
+func (q *queue) retry(d *Delivery, attemptErr error, now time.Time, maxAttempts int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	d.Attempts++
+	d.LastError = attemptErr.Error()
+	if d.Attempts < maxAttempts {
+		d.NextAttempt = now.Add(backoff(d.Attempts)).Unix()
+		return q.save()
+	}
+	q.remove(d)
+	if err := q.save(); err != nil {
+		return err
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(q.deadLetter, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(b, '\n'))
+	return err
+}
+
+func (q *queue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.Pending)
+}
+
+// deadLetters reads the dead letters, and empties the file if requeue is set
+// so they can be pushed again.
+func (q *queue) deadLetters(requeue bool) ([]*Delivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	b, err := ioutil.ReadFile(q.deadLetter)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []*Delivery
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	for decoder.More() {
+		var d Delivery
+		if err = decoder.Decode(&d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if requeue {
+		for _, d := range deliveries {
+			d.Attempts = 0
+			d.NextAttempt = 0
+		}
+		q.Pending = append(q.Pending, deliveries...)
+		if err = q.save(); err != nil {
+			return nil, err
+		}
+		err = os.Remove(q.deadLetter)
+	}
+	return deliveries, err
+}
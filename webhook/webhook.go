@@ -0,0 +1,100 @@
+// Package webhook posts incoming messages as json to configured urls,
+// with HMAC signatures and a retry queue persisted to disk.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultTimeout     = 10 * time.Second
+)
+
+// Filter selects the messages posted to a webhook, empty conditions match everything.
+type Filter struct {
+	Categories []string // friend, group, mp or system
+	Types      []string // message types as in export.Kinds
+	Senders    []string
+	Groups     []string
+	AtOnly     bool // only group messages that @ me
+	Self       bool // also post my own messages
+}
+
+type Config struct {
+	Name        string
+	URL         string
+	Secret      string `json:",omitempty"` // key of the X-Wx-Signature HMAC
+	Filter      Filter
+	MaxAttempts int `json:",omitempty"` // attempts before dead-lettering, 5 by default
+}
+
+func (c *Config) maxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return defaultMaxAttempts
+	}
+	return c.MaxAttempts
+}
+
+// Payload is the normalized json form of a message.
+type Payload struct {
+	MsgId         string
+	Category      string
+	Type          string
+	Sender        string
+	SenderInGroup string `json:",omitempty"`
+	Receiver      string
+	Group         string `json:",omitempty"`
+	Content       string
+	MediaURL      string `json:",omitempty"`
+	At            bool
+	IsSelf        bool
+	CreateTime    int64
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) Match(p *Payload) bool {
+	if p.IsSelf && !f.Self {
+		return false
+	}
+	if len(f.Categories) > 0 && !containsFold(f.Categories, p.Category) {
+		return false
+	}
+	if len(f.Types) > 0 && !containsFold(f.Types, p.Type) {
+		return false
+	}
+	if len(f.Senders) > 0 && !containsFold(f.Senders, p.Sender) && !containsFold(f.Senders, p.SenderInGroup) {
+		return false
+	}
+	if len(f.Groups) > 0 && (p.Group == "" || !containsFold(f.Groups, p.Group)) {
+		return false
+	}
+	return !f.AtOnly || p.At
+}
+
+// Sign returns the X-Wx-Signature of body sent at timestamp:
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>".
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a received webhook, for receivers written in Go.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
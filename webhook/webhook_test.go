@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	failures int // respond 500 to the first failures requests
+	payloads []*Payload
+	badSigs  int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := ioutil.ReadAll(req.Body)
+	if !Verify("secret", req.Header.Get("X-Wx-Timestamp"), body, req.Header.Get("X-Wx-Signature")) {
+		r.badSigs++
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var p Payload
+	_ = json.Unmarshal(body, &p)
+	r.payloads = append(r.payloads, &p)
+}
+
+func TestForwarderRetry(t *testing.T) {
+	r := &receiver{failures: 1}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	dir := t.TempDir()
+	queueFile, deadFile := filepath.Join(dir, "queue.json"), filepath.Join(dir, "dead.jsonl")
+	configs := []*Config{
+		{Name: "ok", URL: server.URL, Secret: "secret", Filter: Filter{Types: []string{"text"}}},
+		{Name: "down", URL: server.URL + "/x", Secret: "wrong", MaxAttempts: 2},
+	}
+	f, err := NewForwarder(configs, queueFile, deadFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Enqueue(&Payload{MsgId: "1", Type: "text", Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Enqueue(&Payload{MsgId: "2", Type: "image", IsSelf: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	f.Flush(now)
+	if len(r.payloads) != 0 || f.queue.pending() != 2 {
+		t.Fatalf("first attempt: got %d payloads, %d pending", len(r.payloads), f.queue.pending())
+	}
+
+	// the queue survives a restart
+	f, err = NewForwarder(configs, queueFile, deadFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Flush(now)
+	if len(r.payloads) != 0 {
+		t.Fatal("retried before the backoff")
+	}
+	f.Flush(now.Add(backoff(1)))
+	if len(r.payloads) != 1 || r.payloads[0].Content != "hi" {
+		t.Fatalf("payloads = %+v, want the text message once", r.payloads)
+	}
+	if r.badSigs != 2 {
+		t.Errorf("bad signatures = %d, want 2", r.badSigs)
+	}
+
+	status, err := f.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Pending != 0 || status.Dead != 1 {
+		t.Errorf("status = %+v, want the failing delivery dead-lettered", status)
+	}
+	n, err := f.RequeueDeadLetters()
+	if err != nil || n != 1 || f.queue.pending() != 1 {
+		t.Errorf("requeue = %d %v, pending %d", n, err, f.queue.pending())
+	}
+}
+
+func TestFilter(t *testing.T) {
+	p := &Payload{Category: "group", Type: "text", Sender: "Team", SenderInGroup: "alice", Group: "Team", At: true}
+	cases := []struct {
+		filter Filter
+		want   bool
+	}{
+		{Filter{}, true},
+		{Filter{Categories: []string{"friend"}}, false},
+		{Filter{Senders: []string{"Alice"}}, true},
+		{Filter{Groups: []string{"other"}}, false},
+		{Filter{AtOnly: true, Types: []string{"text"}}, true},
+	}
+	for i, c := range cases {
+		if got := c.filter.Match(p); got != c.want {
+			t.Errorf("case %d: Match = %v, want %v", i, got, c.want)
+		}
+	}
+}
+
+func TestMediaHandlerSignature(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "1.jpg")
+	if err := ioutil.WriteFile(path, []byte("jpg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(MediaHandler("secret", func(msgId string) (string, error) {
+		return path, nil
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		url  string
+		want int
+	}{
+		{MediaURL(server.URL, "secret", "1"), http.StatusOK},
+		{server.URL + "/media/1", http.StatusForbidden},
+		{MediaURL(server.URL, "wrong", "1"), http.StatusForbidden},
+		{server.URL + "/media/2?sig=" + SignMedia("secret", "1"), http.StatusForbidden},
+	}
+	for i, c := range cases {
+		resp, err := http.Get(c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.want {
+			t.Errorf("case %d: status = %d, want %d", i, resp.StatusCode, c.want)
+		}
+	}
+}